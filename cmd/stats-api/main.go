@@ -10,8 +10,10 @@ import (
 	"syscall"
 	"time"
 
+	"go_test/internal/auth"
 	"go_test/internal/config"
 	"go_test/internal/database"
+	"go_test/internal/notify"
 	"go_test/internal/stats"
 )
 
@@ -26,7 +28,46 @@ func main() {
 	}
 	defer db.Close()
 
-	handler := stats.NewHandler(stats.NewStore(db), logger)
+	weights, err := stats.ParsePriorityWeights(cfg.LeaderboardWeights)
+	if err != nil {
+		logger.Fatalf("invalid LEADERBOARD_WEIGHTS: %v", err)
+	}
+
+	store := stats.NewStore(db)
+	if cfg.DatabaseReplicaURL != "" {
+		// 副本不可用时不阻止启动，统计查询全部走主库
+		replica, err := database.OpenReplica(cfg.DatabaseReplicaURL, logger)
+		if err != nil {
+			logger.Printf("replica connect failed, using primary only: %v", err)
+		} else {
+			defer replica.Close()
+			store.WithReplica(replica)
+		}
+	}
+	// 后台任务共享同一个 context，退出时统一取消
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	handler := stats.NewHandler(store, logger).
+		WithAuthenticator(auth.NewFromConfig(jobsCtx, cfg, db, logger)).
+		WithCacheTTL(cfg.StatsCacheTTL).
+		WithPriorityWeights(weights).
+		WithQueryLimits(cfg.StatsQueryTimeout, cfg.StatsQueryMaxCost)
+
+	go stats.NewReconciler(store, logger, cfg.StatsReconcileInterval).Run(jobsCtx)
+	go stats.NewReplicaMonitor(store, logger, cfg.ReplicaCheckInterval, cfg.ReplicaMaxLag).Run(jobsCtx)
+
+	if sink := newReportSink(cfg, logger); sink != nil {
+		location, err := time.LoadLocation(cfg.ReportTZ)
+		if err != nil {
+			logger.Fatalf("invalid REPORT_TZ: %v", err)
+		}
+		reporter, err := stats.NewReporter(store, sink, cfg.ReportFormat, location, cfg.ReportInterval, logger)
+		if err != nil {
+			logger.Fatalf("invalid report config: %v", err)
+		}
+		go reporter.Run(jobsCtx)
+	}
 
 	srv := &http.Server{
 		Addr:         cfg.Addr,
@@ -50,6 +91,7 @@ func main() {
 	<-stop
 
 	logger.Println("shutting down")
+	stopJobs()
 	// 给予超时时间完成正在处理的请求
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -57,3 +99,27 @@ func main() {
 		logger.Printf("shutdown error: %v", err)
 	}
 }
+
+func newReportSink(cfg config.Config, logger *log.Logger) stats.ReportSink {
+	// 根据 REPORT_SINK 选择报表投递方式，未配置时不启用定时报表
+	switch cfg.ReportSink {
+	case "":
+		return nil
+	case "file":
+		return stats.NewFileSink(cfg.ReportDir)
+	case "webhook":
+		return stats.NewNotifierSink(notify.NewWebhookNotifier(cfg.ReportWebhookURL), cfg.ReportEmailTo)
+	case "smtp":
+		return stats.NewNotifierSink(notify.NewSMTPNotifier(notify.SMTPConfig{
+			Addr:     cfg.SMTP.Addr,
+			From:     cfg.SMTP.From,
+			Username: cfg.SMTP.Username,
+			Password: cfg.SMTP.Password,
+		}), cfg.ReportEmailTo)
+	case "log":
+		return stats.NewNotifierSink(notify.NewLogNotifier(logger), cfg.ReportEmailTo)
+	default:
+		logger.Printf("unknown report sink %q, scheduled reports disabled", cfg.ReportSink)
+		return nil
+	}
+}
@@ -10,8 +10,10 @@ import (
 	"syscall"
 	"time"
 
+	"go_test/internal/auth"
 	"go_test/internal/config"
 	"go_test/internal/database"
+	"go_test/internal/notify"
 	"go_test/internal/todo"
 )
 
@@ -26,7 +28,20 @@ func main() {
 	}
 	defer db.Close()
 
-	handler := todo.NewHandler(todo.NewStore(db), logger)
+	// 后台任务共享同一个 context，退出时统一取消
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	store := todo.NewStore(db)
+	handler := todo.NewHandler(store, logger).WithAuthenticator(auth.NewFromConfig(jobsCtx, cfg, db, logger))
+	if cfg.EmailVerification == "limited" {
+		handler.WithVerifiedWrites()
+	}
+
+	scheduler := todo.NewReminderScheduler(store, newNotifier(cfg, logger), logger, cfg.ReminderInterval)
+	go scheduler.Run(jobsCtx)
+	go todo.NewUnsnoozer(store, logger, cfg.UnsnoozeInterval).Run(jobsCtx)
+	go todo.NewArchiver(store, logger, cfg.ArchiveInterval).Run(jobsCtx)
 
 	srv := &http.Server{
 		Addr:         cfg.Addr,
@@ -50,6 +65,7 @@ func main() {
 	<-stop
 
 	logger.Println("shutting down")
+	stopJobs()
 	// 给予超时时间完成正在处理的请求
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -57,3 +73,23 @@ func main() {
 		logger.Printf("shutdown error: %v", err)
 	}
 }
+
+func newNotifier(cfg config.Config, logger *log.Logger) notify.Notifier {
+	// 根据 NOTIFIER 选择提醒投递渠道，未知取值回退到日志
+	switch cfg.Notifier {
+	case "webhook":
+		return notify.NewWebhookNotifier(cfg.NotifyWebhookURL)
+	case "smtp":
+		return notify.NewSMTPNotifier(notify.SMTPConfig{
+			Addr:      cfg.SMTP.Addr,
+			From:      cfg.SMTP.From,
+			Username:  cfg.SMTP.Username,
+			Password:  cfg.SMTP.Password,
+			DefaultTo: cfg.NotifyEmailTo,
+		})
+	case "log":
+	default:
+		logger.Printf("unknown notifier %q, falling back to log", cfg.Notifier)
+	}
+	return notify.NewLogNotifier(logger)
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -12,6 +13,10 @@ import (
 
 	"go_test/internal/config"
 	"go_test/internal/database"
+	"go_test/internal/jwt"
+	"go_test/internal/mail"
+	"go_test/internal/notify"
+	"go_test/internal/pwpolicy"
 	"go_test/internal/user"
 )
 
@@ -25,7 +30,80 @@ func main() {
 	}
 	defer db.Close()
 
-	handler := user.NewHandler(user.NewStore(db), logger)
+	store := user.NewStore(db)
+	if cfg.TokenDualWrite {
+		store.WithPlaintextTokens()
+	}
+	handler := user.NewHandler(store, logger).
+		WithTokenTTL(cfg.AccessTokenTTL, cfg.RefreshTokenTTL).
+		WithTOTPIssuer(cfg.TOTPIssuer).
+		WithLoginPolicy(user.LoginPolicy{
+			MaxFailures:   cfg.LoginMaxFailures,
+			Lockout:       cfg.LoginLockout,
+			Window:        cfg.LoginFailureWindow,
+			IPMaxFailures: cfg.LoginIPMaxFailures,
+			MaxDelay:      cfg.LoginMaxDelay,
+			UnlockTTL:     cfg.LoginUnlockTTL,
+		}).
+		WithForgotPolicy(user.ForgotPolicy{
+			MaxPerEmail: cfg.ForgotMaxPerEmail,
+			MaxPerIP:    cfg.ForgotMaxPerIP,
+			Window:      cfg.ForgotWindow,
+		})
+	mode, err := user.ParseVerificationMode(cfg.EmailVerification)
+	if err != nil {
+		logger.Fatalf("invalid EMAIL_VERIFICATION: %v", err)
+	}
+	handler.WithEmailVerification(mode, cfg.EmailVerifyTTL, cfg.EmailResendInterval)
+
+	// 后台任务在 HTTP 服务关闭后才取消，正在处理的请求仍可以投递邮件
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	// 账户邮件异步投递，请求不等待 SMTP
+	mailer, err := newMailer(cfg)
+	if err != nil {
+		logger.Fatalf("invalid MAILER: %v", err)
+	}
+	queue := mail.NewQueue(mailer, logger, cfg.MailQueueSize, cfg.MailMaxAttempts, cfg.MailRetryBackoff)
+	go queue.Run(jobsCtx)
+	go user.NewAttemptPruner(store, logger, time.Hour, cfg.AuthAttemptRetention).Run(jobsCtx)
+	handler.WithMailer(queue)
+	if cfg.AppEnv == "dev" {
+		handler.WithDevMode()
+		logger.Println("dev mode: password reset tokens are returned in responses")
+	}
+	passwordPolicy := pwpolicy.Policy{
+		MinLength:        cfg.PasswordMinLength,
+		MaxLength:        cfg.PasswordMaxLength,
+		MinClasses:       cfg.PasswordMinClasses,
+		MinStrength:      cfg.PasswordMinStrength,
+		DisallowPersonal: cfg.PasswordDisallowPersonal,
+	}
+	if cfg.PasswordBreachDir != "" {
+		breached, err := pwpolicy.NewBreachList(cfg.PasswordBreachDir)
+		if err != nil {
+			logger.Fatalf("load breached password list failed: %v", err)
+		}
+		passwordPolicy.Breached = breached
+	}
+	handler.WithPasswordPolicy(passwordPolicy)
+	if cfg.JWTKeysDir != "" {
+		keys, err := jwt.LoadKeyDir(cfg.JWTKeysDir)
+		if err != nil {
+			logger.Fatalf("load jwt keys failed: %v", err)
+		}
+		signer, err := jwt.NewSigner(keys, cfg.JWTActiveKID, cfg.JWTIssuer, cfg.JWTAudience)
+		if err != nil {
+			logger.Fatalf("jwt signer: %v", err)
+		}
+		claims, err := user.ParseJWTClaims(cfg.JWTClaims)
+		if err != nil {
+			logger.Fatalf("invalid JWT_CLAIMS: %v", err)
+		}
+		handler.WithJWT(signer, claims)
+		logger.Printf("issuing jwt access tokens with %d key(s)", len(keys))
+	}
 
 	srv := &http.Server{
 		Addr:         cfg.Addr,
@@ -53,3 +131,28 @@ func main() {
 		logger.Printf("shutdown error: %v", err)
 	}
 }
+
+func newMailer(cfg config.Config) (mail.Mailer, error) {
+	// 根据 MAILER 选择账户邮件的投递方式；只有 dev 环境可以不配置（默认写文件），
+	// 避免生产环境把重置密码等链接静默写到本地目录
+	name := cfg.Mailer
+	if name == "" {
+		if cfg.AppEnv != "dev" {
+			return nil, errors.New("MAILER must be set explicitly (file or smtp) unless APP_ENV=dev")
+		}
+		name = "file"
+	}
+	switch name {
+	case "smtp":
+		return mail.NewSMTPMailer(notify.SMTPConfig{
+			Addr:     cfg.SMTP.Addr,
+			From:     cfg.SMTP.From,
+			Username: cfg.SMTP.Username,
+			Password: cfg.SMTP.Password,
+		}), nil
+	case "file":
+		return mail.NewFileMailer(cfg.MailDir, cfg.SMTP.From), nil
+	default:
+		return nil, fmt.Errorf("unknown mailer %q", name)
+	}
+}
@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+var ErrUnauthorized = errors.New("unauthorized")
+
+const RoleAdmin = "admin"
+
+// Identity 是通过认证的调用方
+type Identity struct {
+	UserID        int64
+	Email         string
+	Role          string
+	EmailVerified bool
+}
+
+func (i Identity) IsAdmin() bool {
+	return i.Role == RoleAdmin
+}
+
+// Authenticator 把 Bearer token 解析为调用方身份
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (Identity, error)
+}
+
+// SessionAuthenticator 直接查询 user-api 写入的 user_sessions 表（按 token 摘要查找），供共享同一数据库的服务使用
+type SessionAuthenticator struct {
+	db *sql.DB
+}
+
+func NewSessionAuthenticator(db *sql.DB) *SessionAuthenticator {
+	return &SessionAuthenticator{db: db}
+}
+
+func (a *SessionAuthenticator) Authenticate(ctx context.Context, token string) (Identity, error) {
+	var identity Identity
+	row := a.db.QueryRowContext(ctx, `
+		SELECT u.id, u.email, u.role, u.email_verified_at IS NOT NULL
+		FROM user_sessions s
+		JOIN users u ON u.id = s.user_id
+		WHERE s.token_hash = $1 AND s.expires_at > NOW() AND s.revoked_at IS NULL
+	`, HashToken(token))
+	if err := row.Scan(&identity.UserID, &identity.Email, &identity.Role, &identity.EmailVerified); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Identity{}, ErrUnauthorized
+		}
+		return Identity{}, err
+	}
+	return identity, nil
+}
+
+// BearerToken 从 Authorization 头中取出 token，缺失时返回空串
+func BearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+}
+
+type contextKey struct{}
+
+// WithIdentity 把身份写入 context
+func WithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, identity)
+}
+
+// FromContext 读取 Middleware 写入的身份
+func FromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(contextKey{}).(Identity)
+	return identity, ok
+}
+
+// Middleware 是可选认证：没有 token 的请求按匿名放行，带了 token 但无效时返回 401
+func Middleware(authenticator Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := BearerToken(r)
+			if token == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			identity, err := authenticator.Authenticate(r.Context(), token)
+			if err != nil {
+				status := http.StatusInternalServerError
+				message := "failed to authenticate"
+				if errors.Is(err, ErrUnauthorized) {
+					status = http.StatusUnauthorized
+					message = "unauthorized"
+				}
+				writeError(w, status, message)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), identity)))
+		})
+	}
+}
+
+// Require 拒绝没有通过认证的请求，需放在 Middleware 之后
+func Require(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := FromContext(r.Context()); !ok {
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireAdmin 只允许管理员访问，需放在 Middleware 之后
+func RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, ok := FromContext(r.Context())
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		if !identity.IsAdmin() {
+			writeError(w, http.StatusForbidden, "forbidden")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireVerifiedForWrites 拒绝邮箱未验证用户的写请求，读请求与匿名请求不受影响，需放在 Middleware 之后
+func RequireVerifiedForWrites(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+		default:
+			if identity, ok := FromContext(r.Context()); ok && !identity.EmailVerified {
+				writeError(w, http.StatusForbidden, "email not verified")
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
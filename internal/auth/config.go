@@ -0,0 +1,28 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"log"
+
+	"go_test/internal/config"
+	"go_test/internal/jwt"
+)
+
+// NewFromConfig 按配置组装 todo-api、stats-api 共用的认证方式：
+// 配置了 JWT_JWKS_URL 时离线校验 JWT，旧的随机 token 仍查询会话表；
+// JWKS 的后台刷新随 ctx 取消而停止
+func NewFromConfig(ctx context.Context, cfg config.Config, db *sql.DB, logger *log.Logger) Authenticator {
+	sessions := NewSessionAuthenticator(db)
+	if cfg.JWTJWKSURL == "" {
+		return sessions
+	}
+
+	fetcher := jwt.NewJWKSFetcher(cfg.JWTJWKSURL, jwt.NewVerifier(cfg.JWTIssuer, cfg.JWTAudience), logger, cfg.JWTJWKSRefresh)
+	if err := fetcher.Refresh(ctx); err != nil {
+		// user-api 暂不可用时不阻止启动，后台刷新或遇到未知 kid 时会重试
+		logger.Printf("jwks initial fetch failed: %v", err)
+	}
+	go fetcher.Run(ctx)
+	return NewJWTAuthenticator(fetcher, sessions)
+}
@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"context"
+	"strconv"
+
+	"go_test/internal/jwt"
+)
+
+// TokenVerifier 离线校验 JWT，由 jwt.JWKSFetcher 实现
+type TokenVerifier interface {
+	Verify(ctx context.Context, token string) (jwt.Claims, error)
+}
+
+// JWTAuthenticator 用公钥校验 user-api 签发的 JWT，不需要查询数据库；
+// 不是 JWT 格式的 token（未启用 JWT 时签发的随机 token）交给 fallback。
+// JWT 在过期前始终有效，登出与吊销只能在 access token 过期后生效
+type JWTAuthenticator struct {
+	verifier TokenVerifier
+	fallback Authenticator
+}
+
+// NewJWTAuthenticator 的 fallback 可以为 nil，此时只接受 JWT
+func NewJWTAuthenticator(verifier TokenVerifier, fallback Authenticator) *JWTAuthenticator {
+	return &JWTAuthenticator{verifier: verifier, fallback: fallback}
+}
+
+func (a *JWTAuthenticator) Authenticate(ctx context.Context, token string) (Identity, error) {
+	if !jwt.IsJWT(token) {
+		if a.fallback == nil {
+			return Identity{}, ErrUnauthorized
+		}
+		return a.fallback.Authenticate(ctx, token)
+	}
+
+	claims, err := a.verifier.Verify(ctx, token)
+	if err != nil {
+		return Identity{}, ErrUnauthorized
+	}
+	userID, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil || userID < 1 {
+		return Identity{}, ErrUnauthorized
+	}
+	return Identity{UserID: userID, Email: claims.Email, Role: claims.Role, EmailVerified: claims.EmailVerified}, nil
+}
@@ -0,0 +1,13 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// HashToken 返回 token 的 SHA-256 摘要（十六进制）。会话、刷新与重置 token 都是 32 字节随机数，
+// 无法靠字典反推，数据库中只保存摘要即可，不需要额外的服务端密钥
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
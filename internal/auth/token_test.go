@@ -0,0 +1,12 @@
+package auth
+
+import "testing"
+
+func TestHashToken(t *testing.T) {
+	// 与迁移中 encode(sha256(convert_to(token, 'UTF8')), 'hex') 的结果一致
+	got := HashToken("abc")
+	want := "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+	if got != want {
+		t.Fatalf("HashToken(abc) = %s, want %s", got, want)
+	}
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"strconv"
 	"time"
 )
 
@@ -11,6 +12,94 @@ type Config struct {
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
 	IdleTimeout  time.Duration
+
+	// 后台任务与通知渠道（todo-api 使用）
+	Notifier         string
+	NotifyWebhookURL string
+	NotifyEmailTo    string
+	ReminderInterval time.Duration
+	UnsnoozeInterval time.Duration
+	ArchiveInterval  time.Duration
+	SMTP             SMTPConfig
+
+	// 登录会话（user-api 使用）：短期 access token 与可轮换的长期 refresh token
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	// TokenDualWrite 为 true 时同时写入明文 token 列，只在 016 与 017 之间的滚动升级期间开启
+	TokenDualWrite bool
+
+	// JWT access token：user-api 配置 JWTKeysDir 时签发 JWT，其余服务配置 JWTJWKSURL 时离线校验
+	JWTKeysDir     string
+	JWTActiveKID   string
+	JWTIssuer      string
+	JWTAudience    string
+	JWTClaims      string
+	JWTJWKSURL     string
+	JWTJWKSRefresh time.Duration
+
+	// 邮箱验证与账户邮件（user-api 使用）；EmailVerification 为 off/limited/required，
+	// todo-api 在 limited 时禁止未验证用户写入
+	EmailVerification   string
+	EmailVerifyTTL      time.Duration
+	EmailResendInterval time.Duration
+	Mailer              string
+	MailDir             string
+	MailQueueSize       int
+	MailMaxAttempts     int
+	MailRetryBackoff    time.Duration
+
+	// 登录与忘记密码限流（user-api 使用），尝试记录保存在数据库中，多实例共享
+	LoginMaxFailures     int
+	LoginLockout         time.Duration
+	LoginFailureWindow   time.Duration
+	LoginIPMaxFailures   int
+	LoginMaxDelay        time.Duration
+	LoginUnlockTTL       time.Duration
+	ForgotMaxPerEmail    int
+	ForgotMaxPerIP       int
+	ForgotWindow         time.Duration
+	AuthAttemptRetention time.Duration
+
+	// TOTPIssuer 显示在验证器 App 中的服务名称（user-api 使用）
+	TOTPIssuer string
+
+	// 密码策略（user-api 使用）：注册、重置与修改密码时校验；PasswordBreachDir 为空时不检查泄露列表
+	PasswordMinLength        int
+	PasswordMaxLength        int
+	PasswordMinClasses       int
+	PasswordMinStrength      int
+	PasswordDisallowPersonal bool
+	PasswordBreachDir        string
+
+	// AppEnv 为 dev 时 user-api 在忘记密码接口中直接返回重置 token
+	AppEnv string
+
+	// 只读副本、统计缓存与对账（stats-api 使用），DatabaseReplicaURL 为空时只用主库
+	DatabaseReplicaURL     string
+	ReplicaCheckInterval   time.Duration
+	ReplicaMaxLag          time.Duration
+	StatsCacheTTL          time.Duration
+	StatsReconcileInterval time.Duration
+	StatsQueryTimeout      time.Duration
+	StatsQueryMaxCost      float64
+	// LeaderboardWeights 形如 "low=1,medium=2,high=3,urgent=5"，为空时使用默认权重
+	LeaderboardWeights string
+
+	// 定时报表（stats-api 使用），ReportSink 为空时不启用
+	ReportSink       string
+	ReportFormat     string
+	ReportInterval   time.Duration
+	ReportTZ         string
+	ReportDir        string
+	ReportWebhookURL string
+	ReportEmailTo    string
+}
+
+type SMTPConfig struct {
+	Addr     string
+	From     string
+	Username string
+	Password string
 }
 
 func Load(defaultAddr string) Config {
@@ -21,6 +110,79 @@ func Load(defaultAddr string) Config {
 		ReadTimeout:  getEnvDuration("READ_TIMEOUT", 5*time.Second),
 		WriteTimeout: getEnvDuration("WRITE_TIMEOUT", 10*time.Second),
 		IdleTimeout:  getEnvDuration("IDLE_TIMEOUT", 120*time.Second),
+
+		Notifier:         getEnv("NOTIFIER", "log"),
+		NotifyWebhookURL: getEnv("NOTIFY_WEBHOOK_URL", ""),
+		NotifyEmailTo:    getEnv("NOTIFY_EMAIL_TO", ""),
+		ReminderInterval: getEnvDuration("REMINDER_INTERVAL", 30*time.Second),
+		UnsnoozeInterval: getEnvDuration("UNSNOOZE_INTERVAL", time.Minute),
+		ArchiveInterval:  getEnvDuration("ARCHIVE_INTERVAL", time.Hour),
+		SMTP: SMTPConfig{
+			Addr:     getEnv("SMTP_ADDR", "localhost:1025"),
+			From:     getEnv("SMTP_FROM", "no-reply@localhost"),
+			Username: getEnv("SMTP_USERNAME", ""),
+			Password: getEnv("SMTP_PASSWORD", ""),
+		},
+
+		AccessTokenTTL:  getEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL: getEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		TokenDualWrite:  getEnvBool("TOKEN_DUAL_WRITE", false),
+
+		JWTKeysDir:     getEnv("JWT_KEYS_DIR", ""),
+		JWTActiveKID:   getEnv("JWT_ACTIVE_KID", ""),
+		JWTIssuer:      getEnv("JWT_ISSUER", "user-api"),
+		JWTAudience:    getEnv("JWT_AUDIENCE", "go-api"),
+		JWTClaims:      getEnv("JWT_CLAIMS", "email"),
+		JWTJWKSURL:     getEnv("JWT_JWKS_URL", ""),
+		JWTJWKSRefresh: getEnvDuration("JWT_JWKS_REFRESH", 10*time.Minute),
+
+		EmailVerification:   getEnv("EMAIL_VERIFICATION", "off"),
+		EmailVerifyTTL:      getEnvDuration("EMAIL_VERIFY_TTL", 24*time.Hour),
+		EmailResendInterval: getEnvDuration("EMAIL_RESEND_INTERVAL", time.Minute),
+		Mailer:              getEnv("MAILER", ""),
+		MailDir:             getEnv("MAIL_DIR", "mail"),
+		MailQueueSize:       getEnvInt("MAIL_QUEUE_SIZE", 100),
+		MailMaxAttempts:     getEnvInt("MAIL_MAX_ATTEMPTS", 5),
+		MailRetryBackoff:    getEnvDuration("MAIL_RETRY_BACKOFF", 2*time.Second),
+
+		LoginMaxFailures:     getEnvInt("LOGIN_MAX_FAILURES", 5),
+		LoginLockout:         getEnvDuration("LOGIN_LOCKOUT", 15*time.Minute),
+		LoginFailureWindow:   getEnvDuration("LOGIN_FAILURE_WINDOW", time.Hour),
+		LoginIPMaxFailures:   getEnvInt("LOGIN_IP_MAX_FAILURES", 50),
+		LoginMaxDelay:        getEnvDuration("LOGIN_MAX_DELAY", 30*time.Second),
+		LoginUnlockTTL:       getEnvDuration("LOGIN_UNLOCK_TTL", time.Hour),
+		ForgotMaxPerEmail:    getEnvInt("FORGOT_MAX_PER_EMAIL", 3),
+		ForgotMaxPerIP:       getEnvInt("FORGOT_MAX_PER_IP", 20),
+		ForgotWindow:         getEnvDuration("FORGOT_WINDOW", time.Hour),
+		AuthAttemptRetention: getEnvDuration("AUTH_ATTEMPT_RETENTION", 7*24*time.Hour),
+
+		TOTPIssuer: getEnv("TOTP_ISSUER", "go-api"),
+
+		PasswordMinLength:        getEnvInt("PASSWORD_MIN_LENGTH", 8),
+		PasswordMaxLength:        getEnvInt("PASSWORD_MAX_LENGTH", 64),
+		PasswordMinClasses:       getEnvInt("PASSWORD_MIN_CLASSES", 0),
+		PasswordMinStrength:      getEnvInt("PASSWORD_MIN_STRENGTH", 0),
+		PasswordDisallowPersonal: getEnvBool("PASSWORD_DISALLOW_PERSONAL", true),
+		PasswordBreachDir:        getEnv("PASSWORD_BREACH_DIR", ""),
+
+		AppEnv: getEnv("APP_ENV", "production"),
+
+		DatabaseReplicaURL:     getEnv("DATABASE_REPLICA_URL", ""),
+		ReplicaCheckInterval:   getEnvDuration("REPLICA_CHECK_INTERVAL", 5*time.Second),
+		ReplicaMaxLag:          getEnvDuration("REPLICA_MAX_LAG", 30*time.Second),
+		StatsCacheTTL:          getEnvDuration("STATS_CACHE_TTL", 5*time.Second),
+		StatsReconcileInterval: getEnvDuration("STATS_RECONCILE_INTERVAL", 10*time.Minute),
+		StatsQueryTimeout:      getEnvDuration("STATS_QUERY_TIMEOUT", 5*time.Second),
+		StatsQueryMaxCost:      getEnvFloat("STATS_QUERY_MAX_COST", 100000),
+		LeaderboardWeights:     getEnv("LEADERBOARD_WEIGHTS", ""),
+
+		ReportSink:       getEnv("REPORT_SINK", ""),
+		ReportFormat:     getEnv("REPORT_FORMAT", "text"),
+		ReportInterval:   getEnvDuration("REPORT_INTERVAL", 7*24*time.Hour),
+		ReportTZ:         getEnv("REPORT_TZ", "UTC"),
+		ReportDir:        getEnv("REPORT_DIR", "reports"),
+		ReportWebhookURL: getEnv("REPORT_WEBHOOK_URL", ""),
+		ReportEmailTo:    getEnv("REPORT_EMAIL_TO", ""),
 	}
 }
 
@@ -41,3 +203,33 @@ func getEnvDuration(key string, fallback time.Duration) time.Duration {
 	}
 	return fallback
 }
+
+func getEnvInt(key string, fallback int) int {
+	// 读取整数环境变量
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return fallback
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	// 读取布尔环境变量，接受 true/false/1/0 等 strconv.ParseBool 支持的写法
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			return parsed
+		}
+	}
+	return fallback
+}
+
+func getEnvFloat(key string, fallback float64) float64 {
+	// 读取浮点数环境变量
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+			return parsed
+		}
+	}
+	return fallback
+}
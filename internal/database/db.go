@@ -11,6 +11,27 @@ import (
 
 func Open(dsn string, logger *log.Logger) (*sql.DB, error) {
 	// 初始化数据库连接池并做连通性检查
+	db, err := open(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Println("database connected")
+	return db, nil
+}
+
+func OpenReplica(dsn string, logger *log.Logger) (*sql.DB, error) {
+	// 只读副本与主库使用相同的连接池配置，调用方决定哪些查询走副本
+	db, err := open(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Println("replica connected")
+	return db, nil
+}
+
+func open(dsn string) (*sql.DB, error) {
 	db, err := sql.Open("pgx", dsn)
 	if err != nil {
 		return nil, err
@@ -28,7 +49,22 @@ func Open(dsn string, logger *log.Logger) (*sql.DB, error) {
 		_ = db.Close()
 		return nil, err
 	}
-
-	logger.Println("database connected")
 	return db, nil
 }
+
+func ReplicaLag(ctx context.Context, db *sql.DB) (time.Duration, error) {
+	// 副本落后主库的时间：已回放完收到的全部 WAL 时视为没有延迟，否则按最后回放事务的提交时间计算；
+	// 在数据库内计算，避免应用与数据库之间的时钟偏差；对主库调用时返回 0
+	var seconds float64
+	row := db.QueryRowContext(ctx, `
+		SELECT CASE
+			WHEN NOT pg_is_in_recovery() THEN 0
+			WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+			ELSE COALESCE(EXTRACT(EPOCH FROM NOW() - pg_last_xact_replay_timestamp()), 0)
+		END::float8
+	`)
+	if err := row.Scan(&seconds); err != nil {
+		return 0, err
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
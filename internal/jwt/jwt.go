@@ -0,0 +1,140 @@
+package jwt
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+const (
+	AlgEdDSA = "EdDSA"
+	AlgRS256 = "RS256"
+)
+
+var (
+	ErrMalformed            = errors.New("jwt: malformed token")
+	ErrUnsupportedAlgorithm = errors.New("jwt: unsupported algorithm")
+	ErrUnknownKey           = errors.New("jwt: unknown key id")
+	ErrInvalidSignature     = errors.New("jwt: invalid signature")
+	ErrExpired              = errors.New("jwt: token expired")
+	ErrNotYetValid          = errors.New("jwt: token not yet valid")
+	ErrInvalidClaims        = errors.New("jwt: invalid issuer or audience")
+)
+
+// Claims 是 user-api 签发的 access token 载荷；sub 为用户 ID，email/name 按配置决定是否写入
+type Claims struct {
+	Issuer        string   `json:"iss,omitempty"`
+	Subject       string   `json:"sub"`
+	Audience      Audience `json:"aud,omitempty"`
+	ExpiresAt     int64    `json:"exp"`
+	NotBefore     int64    `json:"nbf,omitempty"`
+	IssuedAt      int64    `json:"iat"`
+	ID            string   `json:"jti,omitempty"`
+	Role          string   `json:"role,omitempty"`
+	EmailVerified bool     `json:"email_verified"`
+	Email         string   `json:"email,omitempty"`
+	Name          string   `json:"name,omitempty"`
+}
+
+// Audience 按 RFC 7519 既可以是单个字符串也可以是字符串数组
+type Audience []string
+
+func (a Audience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+func (a Audience) Contains(value string) bool {
+	for _, item := range a {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid,omitempty"`
+	Typ string `json:"typ,omitempty"`
+}
+
+// IsJWT 粗略判断 token 是否为 JWT 紧凑格式，用于和旧的随机 token 区分
+func IsJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+func encodeSegment(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func sign(key Key, claims Claims) (string, error) {
+	head, err := json.Marshal(header{Alg: key.Algorithm, Kid: key.ID, Typ: "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := encodeSegment(head) + "." + encodeSegment(payload)
+	signature, err := key.sign([]byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + encodeSegment(signature), nil
+}
+
+type parsedToken struct {
+	header       header
+	claims       Claims
+	signingInput string
+	signature    []byte
+}
+
+func parse(token string) (parsedToken, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return parsedToken{}, ErrMalformed
+	}
+
+	var parsed parsedToken
+	head, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return parsedToken{}, ErrMalformed
+	}
+	if err := json.Unmarshal(head, &parsed.header); err != nil {
+		return parsedToken{}, ErrMalformed
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return parsedToken{}, ErrMalformed
+	}
+	if err := json.Unmarshal(payload, &parsed.claims); err != nil {
+		return parsedToken{}, ErrMalformed
+	}
+	parsed.signature, err = base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return parsedToken{}, ErrMalformed
+	}
+	parsed.signingInput = parts[0] + "." + parts[1]
+	return parsed, nil
+}
@@ -0,0 +1,218 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newEd25519Key(t *testing.T, id string) Key {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := NewKey(id, private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// verifierFromJWKS 模拟其他服务：只拿到 JWKS 中发布的公钥
+func verifierFromJWKS(t *testing.T, signer *Signer, issuer, audience string) *Verifier {
+	t.Helper()
+	data, err := json.Marshal(signer.JWKS())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var set JWKS
+	if err := json.Unmarshal(data, &set); err != nil {
+		t.Fatal(err)
+	}
+	keys := make([]Key, 0, len(set.Keys))
+	for _, jwk := range set.Keys {
+		key, err := jwk.Key()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, key)
+	}
+	verifier := NewVerifier(issuer, audience)
+	verifier.SetKeys(keys)
+	return verifier
+}
+
+func TestSignVerifyRoundTrip(t *testing.T) {
+	rsaPrivate, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := NewKey("rsa-1", rsaPrivate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Unix(1_700_000_000, 0)
+	for _, key := range []Key{newEd25519Key(t, "ed-1"), rsaKey} {
+		signer, err := NewSigner([]Key{key}, "", "user-api", "go-api")
+		if err != nil {
+			t.Fatal(err)
+		}
+		token, err := signer.Sign(Claims{Subject: "42", Role: "admin", Email: "a@example.com"}, now, now.Add(15*time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !IsJWT(token) {
+			t.Fatalf("%s: token is not in compact form: %s", key.Algorithm, token)
+		}
+
+		claims, err := verifierFromJWKS(t, signer, "user-api", "go-api").Verify(token, now.Add(time.Minute))
+		if err != nil {
+			t.Fatalf("%s: verify: %v", key.Algorithm, err)
+		}
+		if claims.Subject != "42" || claims.Role != "admin" || claims.Email != "a@example.com" {
+			t.Fatalf("%s: unexpected claims %+v", key.Algorithm, claims)
+		}
+		if claims.ID == "" || claims.ExpiresAt != now.Add(15*time.Minute).Unix() {
+			t.Fatalf("%s: jti/exp not set: %+v", key.Algorithm, claims)
+		}
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	key := newEd25519Key(t, "ed-1")
+	signer, err := NewSigner([]Key{key}, "", "user-api", "go-api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1_700_000_000, 0)
+	token, err := signer.Sign(Claims{Subject: "1", Role: "user"}, now, now.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(token, ".")
+
+	tampered := Claims{Subject: "2", Role: "admin", ExpiresAt: now.Add(time.Hour).Unix()}
+	payload, _ := json.Marshal(tampered)
+	noneHeader := encodeSegment([]byte(`{"alg":"none","kid":"ed-1"}`))
+
+	cases := []struct {
+		name     string
+		token    string
+		verifier *Verifier
+		at       time.Time
+		want     error
+	}{
+		{"expired", token, verifierFromJWKS(t, signer, "", ""), now.Add(2 * time.Minute), ErrExpired},
+		{"within skew", token, verifierFromJWKS(t, signer, "", ""), now.Add(time.Minute + 10*time.Second), nil},
+		{"not yet valid", token, verifierFromJWKS(t, signer, "", ""), now.Add(-time.Hour), ErrNotYetValid},
+		{"wrong audience", token, verifierFromJWKS(t, signer, "user-api", "other"), now, ErrInvalidClaims},
+		{"wrong issuer", token, verifierFromJWKS(t, signer, "other", "go-api"), now, ErrInvalidClaims},
+		{"tampered payload", parts[0] + "." + encodeSegment(payload) + "." + parts[2], verifierFromJWKS(t, signer, "", ""), now, ErrInvalidSignature},
+		{"alg none", noneHeader + "." + parts[1] + ".", verifierFromJWKS(t, signer, "", ""), now, ErrUnsupportedAlgorithm},
+		{"unknown kid", token, NewVerifier("", ""), now, ErrUnknownKey},
+		{"malformed", "a.b", verifierFromJWKS(t, signer, "", ""), now, ErrMalformed},
+	}
+	for _, tc := range cases {
+		_, err := tc.verifier.Verify(tc.token, tc.at)
+		if !errors.Is(err, tc.want) {
+			t.Fatalf("%s: err = %v, want %v", tc.name, err, tc.want)
+		}
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	oldKey := newEd25519Key(t, "2026-01")
+	newKey := newEd25519Key(t, "2026-02")
+	now := time.Unix(1_700_000_000, 0)
+
+	before, err := NewSigner([]Key{oldKey}, "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldToken, err := before.Sign(Claims{Subject: "1"}, now, now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// activeID 为空时使用最后一把密钥，旧密钥仍在 JWKS 中发布
+	after, err := NewSigner([]Key{oldKey, newKey}, "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newToken, err := after.Sign(Claims{Subject: "1"}, now, now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(decodeHeader(t, newToken), `"kid":"2026-02"`) {
+		t.Fatalf("new token not signed with new key: %s", decodeHeader(t, newToken))
+	}
+
+	verifier := verifierFromJWKS(t, after, "", "")
+	for _, token := range []string{oldToken, newToken} {
+		if _, err := verifier.Verify(token, now); err != nil {
+			t.Fatalf("verify after rotation: %v", err)
+		}
+	}
+
+	if _, err := NewSigner([]Key{oldKey, newKey}, "missing", "", ""); err == nil {
+		t.Fatal("expected error for unknown active kid")
+	}
+	if _, err := NewSigner([]Key{oldKey, oldKey}, "", "", ""); err == nil {
+		t.Fatal("expected error for duplicate kid")
+	}
+}
+
+func decodeHeader(t *testing.T, token string) string {
+	t.Helper()
+	data, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestParsePrivateKeyPEM(t *testing.T) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ParsePrivateKeyPEM("ed", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.Algorithm != AlgEdDSA || key.ID != "ed" {
+		t.Fatalf("unexpected key %s/%s", key.ID, key.Algorithm)
+	}
+
+	if _, err := ParsePrivateKeyPEM("bad", []byte("not a pem")); err == nil {
+		t.Fatal("expected error for invalid PEM")
+	}
+}
+
+func TestAudienceJSON(t *testing.T) {
+	var claims Claims
+	if err := json.Unmarshal([]byte(`{"aud":"a"}`), &claims); err != nil || !claims.Audience.Contains("a") {
+		t.Fatalf("single audience: %v %+v", err, claims.Audience)
+	}
+	if err := json.Unmarshal([]byte(`{"aud":["a","b"]}`), &claims); err != nil || !claims.Audience.Contains("b") {
+		t.Fatalf("audience list: %v %+v", err, claims.Audience)
+	}
+	data, err := json.Marshal(Audience{"a"})
+	if err != nil || string(data) != `"a"` {
+		t.Fatalf("marshal single audience = %s, %v", data, err)
+	}
+}
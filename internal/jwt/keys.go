@@ -0,0 +1,198 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const minRSABits = 2048
+
+// Key 是一把带 kid 的签名密钥；只含公钥时只能用于校验
+type Key struct {
+	ID        string
+	Algorithm string
+	private   crypto.Signer
+	public    crypto.PublicKey
+}
+
+// NewKey 根据私钥类型确定算法：Ed25519 使用 EdDSA，RSA 使用 RS256
+func NewKey(id string, private crypto.Signer) (Key, error) {
+	if id == "" {
+		return Key{}, errors.New("jwt: key id is required")
+	}
+	switch k := private.(type) {
+	case ed25519.PrivateKey:
+		return Key{ID: id, Algorithm: AlgEdDSA, private: k, public: k.Public()}, nil
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < minRSABits {
+			return Key{}, fmt.Errorf("jwt: rsa key %q must be at least %d bits", id, minRSABits)
+		}
+		return Key{ID: id, Algorithm: AlgRS256, private: k, public: k.Public()}, nil
+	default:
+		return Key{}, fmt.Errorf("jwt: key %q: %w", id, ErrUnsupportedAlgorithm)
+	}
+}
+
+// ParsePrivateKeyPEM 解析 PKCS#8（Ed25519/RSA）或 PKCS#1（RSA）格式的 PEM 私钥
+func ParsePrivateKeyPEM(id string, data []byte) (Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return Key{}, fmt.Errorf("jwt: key %q: no PEM block found", id)
+	}
+
+	var private any
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return Key{}, fmt.Errorf("jwt: key %q: unsupported PEM type %q", id, block.Type)
+	}
+	if err != nil {
+		return Key{}, fmt.Errorf("jwt: key %q: %w", id, err)
+	}
+
+	signer, ok := private.(crypto.Signer)
+	if !ok {
+		return Key{}, fmt.Errorf("jwt: key %q: %w", id, ErrUnsupportedAlgorithm)
+	}
+	return NewKey(id, signer)
+}
+
+// LoadKeyDir 读取目录下所有 *.pem 私钥，文件名（去掉扩展名）作为 kid，按 kid 排序返回
+func LoadKeyDir(dir string) ([]Key, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	keys := make([]Key, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		id := strings.TrimSuffix(filepath.Base(path), ".pem")
+		key, err := ParsePrivateKeyPEM(id, data)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("jwt: no *.pem keys in %s", dir)
+	}
+	return keys, nil
+}
+
+// Public 返回只含公钥的副本
+func (k Key) Public() Key {
+	return Key{ID: k.ID, Algorithm: k.Algorithm, public: k.public}
+}
+
+func (k Key) sign(input []byte) ([]byte, error) {
+	switch private := k.private.(type) {
+	case ed25519.PrivateKey:
+		return ed25519.Sign(private, input), nil
+	case *rsa.PrivateKey:
+		digest := sha256.Sum256(input)
+		return rsa.SignPKCS1v15(rand.Reader, private, crypto.SHA256, digest[:])
+	default:
+		return nil, fmt.Errorf("jwt: key %q cannot sign", k.ID)
+	}
+}
+
+func (k Key) verify(input, signature []byte) bool {
+	switch public := k.public.(type) {
+	case ed25519.PublicKey:
+		return ed25519.Verify(public, input, signature)
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(input)
+		return rsa.VerifyPKCS1v15(public, crypto.SHA256, digest[:], signature) == nil
+	default:
+		return false
+	}
+}
+
+// JWK 是 RFC 7517 中的单个公钥，只覆盖本服务使用的 OKP(Ed25519) 与 RSA 两种类型
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+// JWKS 是 /.well-known/jwks.json 的响应体
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+func (k Key) JWK() JWK {
+	jwk := JWK{Kid: k.ID, Alg: k.Algorithm, Use: "sig"}
+	switch public := k.public.(type) {
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	}
+	return jwk
+}
+
+// Key 把 JWK 转换为只能用于校验的公钥
+func (j JWK) Key() (Key, error) {
+	if j.Kid == "" {
+		return Key{}, errors.New("jwt: jwk without kid")
+	}
+	if j.Use != "" && j.Use != "sig" {
+		return Key{}, fmt.Errorf("jwt: jwk %q is not a signing key", j.Kid)
+	}
+
+	switch {
+	case j.Kty == "OKP" && j.Crv == "Ed25519" && (j.Alg == "" || j.Alg == AlgEdDSA):
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return Key{}, fmt.Errorf("jwt: jwk %q has invalid x", j.Kid)
+		}
+		return Key{ID: j.Kid, Algorithm: AlgEdDSA, public: ed25519.PublicKey(x)}, nil
+	case j.Kty == "RSA" && (j.Alg == "" || j.Alg == AlgRS256):
+		n, err := base64.RawURLEncoding.DecodeString(j.N)
+		if err != nil {
+			return Key{}, fmt.Errorf("jwt: jwk %q has invalid n", j.Kid)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(j.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return Key{}, fmt.Errorf("jwt: jwk %q has invalid e", j.Kid)
+		}
+		public := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		if public.N.BitLen() < minRSABits {
+			return Key{}, fmt.Errorf("jwt: jwk %q must be at least %d bits", j.Kid, minRSABits)
+		}
+		return Key{ID: j.Kid, Algorithm: AlgRS256, public: public}, nil
+	default:
+		return Key{}, fmt.Errorf("jwt: jwk %q: %w", j.Kid, ErrUnsupportedAlgorithm)
+	}
+}
@@ -0,0 +1,72 @@
+package jwt
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// Signer 用当前密钥签发 token，并通过 JWKS 发布全部公钥：
+// 轮换时新增密钥并切换 activeID，旧密钥保留到它签发的 token 全部过期后再移除
+type Signer struct {
+	active   Key
+	keys     []Key
+	issuer   string
+	audience string
+}
+
+// NewSigner 的 activeID 为空时使用排序后的最后一把密钥（按日期命名 kid 即为最新的一把）
+func NewSigner(keys []Key, activeID, issuer, audience string) (*Signer, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("jwt: no signing keys")
+	}
+
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if seen[key.ID] {
+			return nil, fmt.Errorf("jwt: duplicate key id %q", key.ID)
+		}
+		if key.private == nil {
+			return nil, fmt.Errorf("jwt: key %q has no private key", key.ID)
+		}
+		seen[key.ID] = true
+	}
+
+	if activeID == "" {
+		activeID = keys[len(keys)-1].ID
+	}
+	for _, key := range keys {
+		if key.ID == activeID {
+			return &Signer{active: key, keys: keys, issuer: issuer, audience: audience}, nil
+		}
+	}
+	return nil, fmt.Errorf("jwt: active key %q not found", activeID)
+}
+
+// Sign 填入 iss/aud/iat/nbf/exp/jti 后签名，其余声明由调用方决定
+func (s *Signer) Sign(claims Claims, issuedAt, expiresAt time.Time) (string, error) {
+	claims.Issuer = s.issuer
+	if s.audience != "" {
+		claims.Audience = Audience{s.audience}
+	}
+	claims.IssuedAt = issuedAt.Unix()
+	claims.NotBefore = issuedAt.Unix()
+	claims.ExpiresAt = expiresAt.Unix()
+	if claims.ID == "" {
+		id := make([]byte, 16)
+		if _, err := rand.Read(id); err != nil {
+			return "", err
+		}
+		claims.ID = hex.EncodeToString(id)
+	}
+	return sign(s.active, claims)
+}
+
+func (s *Signer) JWKS() JWKS {
+	set := JWKS{Keys: make([]JWK, 0, len(s.keys))}
+	for _, key := range s.keys {
+		set.Keys = append(set.Keys, key.JWK())
+	}
+	return set
+}
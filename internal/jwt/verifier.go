@@ -0,0 +1,182 @@
+package jwt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// clockSkew 容忍签发方与校验方之间的时钟偏差
+const clockSkew = 30 * time.Second
+
+// Verifier 只用公钥离线校验 token，不访问数据库或 user-api
+type Verifier struct {
+	issuer   string
+	audience string
+
+	mu   sync.RWMutex
+	keys map[string]Key
+}
+
+// NewVerifier 的 issuer/audience 为空时不校验对应声明
+func NewVerifier(issuer, audience string) *Verifier {
+	return &Verifier{issuer: issuer, audience: audience, keys: map[string]Key{}}
+}
+
+// SetKeys 整体替换可用公钥
+func (v *Verifier) SetKeys(keys []Key) {
+	next := make(map[string]Key, len(keys))
+	for _, key := range keys {
+		next[key.ID] = key.Public()
+	}
+	v.mu.Lock()
+	v.keys = next
+	v.mu.Unlock()
+}
+
+func (v *Verifier) Verify(token string, now time.Time) (Claims, error) {
+	parsed, err := parse(token)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	v.mu.RLock()
+	key, ok := v.keys[parsed.header.Kid]
+	v.mu.RUnlock()
+	if !ok {
+		return Claims{}, ErrUnknownKey
+	}
+	// 算法以密钥为准，拒绝 header 中的 none 或与密钥不符的算法
+	if parsed.header.Alg != key.Algorithm {
+		return Claims{}, ErrUnsupportedAlgorithm
+	}
+	if !key.verify([]byte(parsed.signingInput), parsed.signature) {
+		return Claims{}, ErrInvalidSignature
+	}
+
+	claims := parsed.claims
+	if claims.ExpiresAt == 0 || now.After(time.Unix(claims.ExpiresAt, 0).Add(clockSkew)) {
+		return Claims{}, ErrExpired
+	}
+	if claims.NotBefore != 0 && now.Add(clockSkew).Before(time.Unix(claims.NotBefore, 0)) {
+		return Claims{}, ErrNotYetValid
+	}
+	if v.issuer != "" && claims.Issuer != v.issuer {
+		return Claims{}, ErrInvalidClaims
+	}
+	if v.audience != "" && !claims.Audience.Contains(v.audience) {
+		return Claims{}, ErrInvalidClaims
+	}
+	return claims, nil
+}
+
+// minRefetchInterval 限制因未知 kid 触发的补拉频率，避免伪造 kid 的请求打满 user-api
+const minRefetchInterval = 30 * time.Second
+
+// JWKSFetcher 定期从 user-api 拉取 JWKS 更新 Verifier；遇到未知 kid（刚轮换的密钥）时立即补拉一次
+type JWKSFetcher struct {
+	url      string
+	client   *http.Client
+	verifier *Verifier
+	logger   *log.Logger
+	interval time.Duration
+
+	mu        sync.Mutex
+	lastFetch time.Time
+}
+
+func NewJWKSFetcher(url string, verifier *Verifier, logger *log.Logger, interval time.Duration) *JWKSFetcher {
+	return &JWKSFetcher{
+		url:      url,
+		client:   &http.Client{Timeout: 5 * time.Second},
+		verifier: verifier,
+		logger:   logger,
+		interval: interval,
+	}
+}
+
+// Refresh 拉取一次 JWKS；无法识别的密钥跳过并记录日志
+func (f *JWKSFetcher) Refresh(ctx context.Context) error {
+	f.mu.Lock()
+	f.lastFetch = time.Now()
+	f.mu.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("jwt: jwks returned status %d", resp.StatusCode)
+	}
+
+	var set JWKS
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("jwt: decode jwks: %w", err)
+	}
+
+	keys := make([]Key, 0, len(set.Keys))
+	for _, jwk := range set.Keys {
+		key, err := jwk.Key()
+		if err != nil {
+			f.logger.Printf("jwks skip key: %v", err)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return errors.New("jwt: jwks has no usable keys")
+	}
+	f.verifier.SetKeys(keys)
+	return nil
+}
+
+func (f *JWKSFetcher) Run(ctx context.Context) {
+	// 按固定间隔刷新公钥，直到 ctx 被取消
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := f.Refresh(ctx); err != nil && ctx.Err() == nil {
+			f.logger.Printf("jwks refresh error: %v", err)
+		}
+	}
+}
+
+// Verify 校验 token，kid 未知且距上次拉取已超过 minRefetchInterval 时补拉后重试
+func (f *JWKSFetcher) Verify(ctx context.Context, token string) (Claims, error) {
+	claims, err := f.verifier.Verify(token, time.Now())
+	if !errors.Is(err, ErrUnknownKey) {
+		return claims, err
+	}
+
+	f.mu.Lock()
+	stale := time.Since(f.lastFetch) >= minRefetchInterval
+	if stale {
+		f.lastFetch = time.Now()
+	}
+	f.mu.Unlock()
+	if !stale {
+		return Claims{}, err
+	}
+	if refreshErr := f.Refresh(ctx); refreshErr != nil {
+		f.logger.Printf("jwks refresh error: %v", refreshErr)
+		return Claims{}, err
+	}
+	return f.verifier.Verify(token, time.Now())
+}
@@ -0,0 +1,82 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go_test/internal/notify"
+)
+
+// Message 是一封邮件；HTML 不为空时以 multipart/alternative 同时发送纯文本与 HTML 版本
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+	HTML    string
+}
+
+// Mailer 抽象账户相关邮件（验证邮箱、重置密码）的投递方式
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// SMTPMailer 复用 notify 包的 SMTP 连接与认证
+type SMTPMailer struct {
+	notifier *notify.SMTPNotifier
+	from     string
+}
+
+func NewSMTPMailer(cfg notify.SMTPConfig) *SMTPMailer {
+	return &SMTPMailer{notifier: notify.NewSMTPNotifier(cfg), from: cfg.From}
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	data, err := buildMessage(m.from, msg)
+	if err != nil {
+		return err
+	}
+	return m.notifier.SendRaw(ctx, msg.To, data)
+}
+
+// FileMailer 把邮件写成 .eml 文件放入目录，本地开发时不需要 SMTP 服务
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+func NewFileMailer(dir, from string) *FileMailer {
+	return &FileMailer{dir: dir, from: from}
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	// 先写临时文件再重命名，读取方不会看到写了一半的邮件
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), sanitizeFilename(msg.To))
+	path := filepath.Join(m.dir, name)
+	data, err := buildMessage(m.from, msg)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func sanitizeFilename(value string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_', r == '@':
+			return r
+		default:
+			return '_'
+		}
+	}, value)
+}
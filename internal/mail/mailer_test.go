@@ -0,0 +1,103 @@
+package mail
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	netmail "net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go_test/internal/notify"
+	"go_test/internal/notify/smtptest"
+)
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	mailer := NewFileMailer(dir, "no-reply@localhost")
+	if err := mailer.Send(context.Background(), Message{To: "a/b@example.com", Subject: "验证邮箱", Body: "token: abc"}); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expected one .eml file, got %v (%v)", files, err)
+	}
+	if strings.Contains(filepath.Base(files[0]), "/") || !strings.HasSuffix(files[0], "a_b@example.com.eml") {
+		t.Fatalf("unexpected file name %s", files[0])
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"From: no-reply@localhost", "To: a/b@example.com", "Subject: =?utf-8?q?", "token: abc"} {
+		if !strings.Contains(string(data), want) {
+			t.Fatalf("message missing %q:\n%s", want, data)
+		}
+	}
+}
+
+func TestSMTPMailerSendsMultipart(t *testing.T) {
+	server := smtptest.NewServer(t)
+	mailer := NewSMTPMailer(notify.SMTPConfig{Addr: server.Addr(), From: "no-reply@example.com"})
+
+	msg, err := Render(TemplatePasswordReset, "zh", TokenEmail{Name: "Alice", Token: "abc123", ExpiresAt: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg.To = "alice@example.com"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := mailer.Send(ctx, msg); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	select {
+	case got := <-server.Messages:
+		if got.From != "no-reply@example.com" || len(got.To) != 1 || got.To[0] != "alice@example.com" {
+			t.Fatalf("unexpected envelope: %+v", got)
+		}
+		parsed, err := netmail.ReadMessage(strings.NewReader(got.Data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+		if err != nil || mediaType != "multipart/alternative" {
+			t.Fatalf("content type = %q (%v)", parsed.Header.Get("Content-Type"), err)
+		}
+		subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+		if err != nil || subject != "重置密码" {
+			t.Fatalf("subject = %q (%v)", subject, err)
+		}
+
+		reader := multipart.NewReader(parsed.Body, params["boundary"])
+		var types []string
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			content, err := io.ReadAll(part)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(content), "abc123") {
+				t.Fatalf("%s part missing token", part.Header.Get("Content-Type"))
+			}
+			types = append(types, part.Header.Get("Content-Type"))
+		}
+		if len(types) != 2 || !strings.HasPrefix(types[0], "text/plain") || !strings.HasPrefix(types[1], "text/html") {
+			t.Fatalf("unexpected parts %v", types)
+		}
+	case <-ctx.Done():
+		t.Fatal("fake server did not receive a message")
+	}
+}
@@ -0,0 +1,101 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+var ErrQueueFull = errors.New("mail: queue full")
+
+// queueWorkers 是并发发送的协程数，单封邮件重试等待时不会阻塞其他邮件
+const queueWorkers = 4
+
+// sendTimeout 限制单次投递的耗时
+const sendTimeout = 30 * time.Second
+
+// Queue 在后台异步投递邮件，失败后按指数退避重试。队列只在内存中，
+// 进程退出时尚未投递的邮件会丢失（会记录日志），调用方不应依赖邮件一定送达
+type Queue struct {
+	mailer   Mailer
+	logger   *log.Logger
+	messages chan Message
+	attempts int
+	backoff  time.Duration
+}
+
+func NewQueue(mailer Mailer, logger *log.Logger, size, attempts int, backoff time.Duration) *Queue {
+	if attempts < 1 {
+		attempts = 1
+	}
+	return &Queue{
+		mailer:   mailer,
+		logger:   logger,
+		messages: make(chan Message, size),
+		attempts: attempts,
+		backoff:  backoff,
+	}
+}
+
+// Send 只负责入队，队列已满时立即返回 ErrQueueFull
+func (q *Queue) Send(ctx context.Context, msg Message) error {
+	select {
+	case q.messages <- msg:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+func (q *Queue) Run(ctx context.Context) {
+	// 启动固定数量的 worker 消费队列，直到 ctx 被取消
+	var wg sync.WaitGroup
+	for i := 0; i < queueWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case msg := <-q.messages:
+					q.deliver(ctx, msg)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if pending := len(q.messages); pending > 0 {
+		q.logger.Printf("mail queue stopped with %d unsent message(s)", pending)
+	}
+}
+
+func (q *Queue) deliver(ctx context.Context, msg Message) {
+	delay := q.backoff
+	for attempt := 1; ; attempt++ {
+		sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+		err := q.mailer.Send(sendCtx, msg)
+		cancel()
+		if err == nil {
+			return
+		}
+		if attempt >= q.attempts {
+			q.logger.Printf("mail to %s dropped after %d attempt(s): %v", msg.To, attempt, err)
+			return
+		}
+		q.logger.Printf("mail to %s failed (attempt %d/%d), retrying in %s: %v", msg.To, attempt, q.attempts, delay, err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			q.logger.Printf("mail to %s abandoned on shutdown: %v", msg.To, err)
+			return
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
@@ -0,0 +1,104 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyMailer 前 failures 次发送失败，之后成功
+type flakyMailer struct {
+	mu        sync.Mutex
+	failures  int
+	calls     int
+	delivered chan Message
+}
+
+func (m *flakyMailer) Send(ctx context.Context, msg Message) error {
+	m.mu.Lock()
+	m.calls++
+	fail := m.calls <= m.failures
+	m.mu.Unlock()
+	if fail {
+		return errors.New("temporary failure")
+	}
+	m.delivered <- msg
+	return nil
+}
+
+func TestQueueRetriesUntilDelivered(t *testing.T) {
+	mailer := &flakyMailer{failures: 2, delivered: make(chan Message, 1)}
+	queue := NewQueue(mailer, log.New(io.Discard, "", 0), 4, 3, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go queue.Run(ctx)
+
+	if err := queue.Send(ctx, Message{To: "a@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-mailer.delivered:
+		if msg.To != "a@example.com" {
+			t.Fatalf("unexpected message %+v", msg)
+		}
+	case <-ctx.Done():
+		t.Fatal("message was not delivered")
+	}
+	mailer.mu.Lock()
+	defer mailer.mu.Unlock()
+	if mailer.calls != 3 {
+		t.Fatalf("calls = %d, want 3", mailer.calls)
+	}
+}
+
+func TestQueueDropsAfterMaxAttempts(t *testing.T) {
+	mailer := &flakyMailer{failures: 10, delivered: make(chan Message, 1)}
+	queue := NewQueue(mailer, log.New(io.Discard, "", 0), 4, 2, time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		queue.Run(ctx)
+		close(done)
+	}()
+
+	if err := queue.Send(ctx, Message{To: "a@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mailer.mu.Lock()
+		calls := mailer.calls
+		mailer.mu.Unlock()
+		if calls >= 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("calls = %d, want 2", calls)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	mailer.mu.Lock()
+	defer mailer.mu.Unlock()
+	if mailer.calls != 2 {
+		t.Fatalf("calls = %d, want exactly 2", mailer.calls)
+	}
+}
+
+func TestQueueFull(t *testing.T) {
+	queue := NewQueue(&flakyMailer{}, log.New(io.Discard, "", 0), 1, 1, time.Millisecond)
+	if err := queue.Send(context.Background(), Message{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := queue.Send(context.Background(), Message{}); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("err = %v, want ErrQueueFull", err)
+	}
+}
@@ -0,0 +1,117 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+const (
+	TemplatePasswordReset     = "password_reset"
+	TemplateEmailVerification = "email_verification"
+	TemplateAccountUnlock     = "account_unlock"
+
+	DefaultLanguage = "en"
+)
+
+// TokenEmail 是验证邮箱、重置密码等"发送一次性 token"类邮件的模板数据
+type TokenEmail struct {
+	Name      string
+	Token     string
+	ExpiresAt time.Time
+}
+
+// 每个模板文件命名为 <name>.<lang>.tmpl，分别定义 subject、text、html 三个块；
+// 同一文件用 text/template 渲染主题与纯文本，用 html/template 渲染 HTML（自动转义）
+//
+//go:embed templates/*.tmpl
+var templateFiles embed.FS
+
+type localizedTemplate struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+var templates = mustParseTemplates()
+
+func mustParseTemplates() map[string]localizedTemplate {
+	files, err := templateFiles.ReadDir("templates")
+	if err != nil {
+		panic(err)
+	}
+
+	parsed := make(map[string]localizedTemplate, len(files))
+	for _, file := range files {
+		name := path.Join("templates", file.Name())
+		key := strings.TrimSuffix(file.Name(), ".tmpl")
+		parsed[key] = localizedTemplate{
+			text: texttemplate.Must(texttemplate.New(file.Name()).Option("missingkey=error").ParseFS(templateFiles, name)),
+			html: htmltemplate.Must(htmltemplate.New(file.Name()).Option("missingkey=error").ParseFS(templateFiles, name)),
+		}
+	}
+	return parsed
+}
+
+// Render 按语言渲染模板，没有该语言版本时回退到 DefaultLanguage；返回的 Message 未设置收件人
+func Render(name, lang string, data any) (Message, error) {
+	tmpl, ok := templates[name+"."+lang]
+	if !ok {
+		tmpl, ok = templates[name+"."+DefaultLanguage]
+	}
+	if !ok {
+		return Message{}, fmt.Errorf("mail: unknown template %q", name)
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := tmpl.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, err
+	}
+	if err := tmpl.text.ExecuteTemplate(&text, "text", data); err != nil {
+		return Message{}, err
+	}
+	if err := tmpl.html.ExecuteTemplate(&html, "html", data); err != nil {
+		return Message{}, err
+	}
+	return Message{
+		Subject: strings.TrimSpace(subject.String()),
+		Body:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
+
+// MatchLanguage 从 Accept-Language 中选出权重最高且有模板的语言（zh、en），都不匹配时返回 DefaultLanguage
+func MatchLanguage(acceptLanguage string) string {
+	type candidate struct {
+		lang string
+		q    float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(part, ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		q := 1.0
+		for _, param := range fields[1:] {
+			if value, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		base, _, _ := strings.Cut(tag, "-")
+		if q > 0 && (base == "zh" || base == "en") {
+			candidates = append(candidates, candidate{lang: base, q: q})
+		}
+	}
+	if len(candidates) == 0 {
+		return DefaultLanguage
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	return candidates[0].lang
+}
@@ -0,0 +1,64 @@
+package mail
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRender(t *testing.T) {
+	data := TokenEmail{Name: "<Alice>", Token: "abc123", ExpiresAt: time.Date(2026, 1, 2, 3, 4, 0, 0, time.UTC)}
+
+	cases := []struct {
+		name, lang, subject string
+	}{
+		{TemplatePasswordReset, "en", "Reset your password"},
+		{TemplatePasswordReset, "zh", "重置密码"},
+		{TemplatePasswordReset, "fr", "Reset your password"},
+		{TemplateEmailVerification, "en", "Verify your email address"},
+		{TemplateEmailVerification, "zh", "验证你的邮箱"},
+		{TemplateAccountUnlock, "en", "Your account has been temporarily locked"},
+		{TemplateAccountUnlock, "zh", "你的账户已被临时锁定"},
+	}
+	for _, tc := range cases {
+		msg, err := Render(tc.name, tc.lang, data)
+		if err != nil {
+			t.Fatalf("%s/%s: %v", tc.name, tc.lang, err)
+		}
+		if msg.Subject != tc.subject {
+			t.Fatalf("%s/%s: subject = %q, want %q", tc.name, tc.lang, msg.Subject, tc.subject)
+		}
+		for _, body := range []string{msg.Body, msg.HTML} {
+			if !strings.Contains(body, "abc123") || !strings.Contains(body, "2026-01-02 03:04 UTC") {
+				t.Fatalf("%s/%s: body missing token or expiry:\n%s", tc.name, tc.lang, body)
+			}
+		}
+		if !strings.Contains(msg.Body, "<Alice>") || !strings.Contains(msg.HTML, "&lt;Alice&gt;") {
+			t.Fatalf("%s/%s: name should be escaped only in HTML", tc.name, tc.lang)
+		}
+	}
+
+	if _, err := Render("missing", "en", data); err == nil {
+		t.Fatal("expected error for unknown template")
+	}
+}
+
+func TestMatchLanguage(t *testing.T) {
+	cases := []struct {
+		header string
+		want   string
+	}{
+		{"", "en"},
+		{"zh-CN,zh;q=0.9,en;q=0.8", "zh"},
+		{"en-US,en;q=0.9", "en"},
+		{"fr-FR, zh;q=0.5", "zh"},
+		{"en;q=0.4, zh-TW;q=0.6", "zh"},
+		{"zh;q=0, en;q=0.1", "en"},
+		{"de", "en"},
+	}
+	for _, tc := range cases {
+		if got := MatchLanguage(tc.header); got != tc.want {
+			t.Fatalf("MatchLanguage(%q) = %q, want %q", tc.header, got, tc.want)
+		}
+	}
+}
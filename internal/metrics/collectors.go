@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"runtime"
+	"time"
+)
+
+func runtimeCollector(start time.Time) Collector {
+	// Go 运行时指标：goroutine 数、内存与 GC
+	return func(ctx context.Context) ([]Sample, error) {
+		var mem runtime.MemStats
+		runtime.ReadMemStats(&mem)
+
+		return []Sample{
+			{Name: "go_goroutines", Help: "Number of goroutines that currently exist.", Type: TypeGauge, Value: float64(runtime.NumGoroutine())},
+			{Name: "go_info", Help: "Information about the Go environment.", Type: TypeGauge, Labels: map[string]string{"version": runtime.Version()}, Value: 1},
+			{Name: "go_memstats_alloc_bytes", Help: "Number of bytes allocated and still in use.", Type: TypeGauge, Value: float64(mem.Alloc)},
+			{Name: "go_memstats_heap_inuse_bytes", Help: "Number of heap bytes that are in use.", Type: TypeGauge, Value: float64(mem.HeapInuse)},
+			{Name: "go_memstats_sys_bytes", Help: "Number of bytes obtained from system.", Type: TypeGauge, Value: float64(mem.Sys)},
+			{Name: "go_gc_cycles_total", Help: "Number of completed GC cycles.", Type: TypeCounter, Value: float64(mem.NumGC)},
+			{Name: "go_gc_pause_seconds_total", Help: "Total GC stop-the-world pause time.", Type: TypeCounter, Value: float64(mem.PauseTotalNs) / 1e9},
+			{Name: "process_start_time_seconds", Help: "Start time of the process since unix epoch in seconds.", Type: TypeGauge, Value: float64(start.Unix())},
+		}, nil
+	}
+}
+
+// DBStatsCollector 输出 database/sql 连接池状态
+func DBStatsCollector(db *sql.DB) Collector {
+	return func(ctx context.Context) ([]Sample, error) {
+		stats := db.Stats()
+		return []Sample{
+			{Name: "db_max_open_connections", Help: "Maximum number of open connections to the database.", Type: TypeGauge, Value: float64(stats.MaxOpenConnections)},
+			{Name: "db_open_connections", Help: "The number of established connections both in use and idle.", Type: TypeGauge, Value: float64(stats.OpenConnections)},
+			{Name: "db_in_use_connections", Help: "The number of connections currently in use.", Type: TypeGauge, Value: float64(stats.InUse)},
+			{Name: "db_idle_connections", Help: "The number of idle connections.", Type: TypeGauge, Value: float64(stats.Idle)},
+			{Name: "db_wait_count_total", Help: "The total number of connections waited for.", Type: TypeCounter, Value: float64(stats.WaitCount)},
+			{Name: "db_wait_duration_seconds_total", Help: "The total time blocked waiting for a new connection.", Type: TypeCounter, Value: stats.WaitDuration.Seconds()},
+			{Name: "db_max_idle_closed_total", Help: "The total number of connections closed due to SetMaxIdleConns.", Type: TypeCounter, Value: float64(stats.MaxIdleClosed)},
+			{Name: "db_max_lifetime_closed_total", Help: "The total number of connections closed due to SetConnMaxLifetime.", Type: TypeCounter, Value: float64(stats.MaxLifetimeClosed)},
+		}, nil
+	}
+}
@@ -0,0 +1,154 @@
+package metrics
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// defaultBuckets 与 Prometheus 客户端默认的延迟分桶一致（单位：秒）
+var defaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type requestKey struct {
+	Method string
+	Route  string
+	Status string
+}
+
+type routeKey struct {
+	Method string
+	Route  string
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+type httpMetrics struct {
+	mu        sync.Mutex
+	requests  map[requestKey]uint64
+	durations map[routeKey]*histogram
+}
+
+func newHTTPMetrics() *httpMetrics {
+	return &httpMetrics{
+		requests:  make(map[requestKey]uint64),
+		durations: make(map[routeKey]*histogram),
+	}
+}
+
+// Middleware 记录请求数与耗时，route 标签使用 chi 的路由模板（如 /todos/{id}），避免 ID 造成标签爆炸
+func (r *Registry) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, req.ProtoMajor)
+		next.ServeHTTP(ww, req)
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(req.Context()); rctx != nil {
+			if pattern := rctx.RoutePattern(); pattern != "" {
+				route = pattern
+			}
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		r.http.observe(req.Method, route, status, time.Since(start))
+	})
+}
+
+func (m *httpMetrics) observe(method, route string, status int, elapsed time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests[requestKey{Method: method, Route: route, Status: strconv.Itoa(status)}]++
+
+	key := routeKey{Method: method, Route: route}
+	h, ok := m.durations[key]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(defaultBuckets))}
+		m.durations[key] = h
+	}
+	seconds := elapsed.Seconds()
+	for i, bound := range defaultBuckets {
+		if seconds <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+func (m *httpMetrics) samples() []Sample {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	requestKeys := make([]requestKey, 0, len(m.requests))
+	for key := range m.requests {
+		requestKeys = append(requestKeys, key)
+	}
+	sort.Slice(requestKeys, func(i, j int) bool {
+		a, b := requestKeys[i], requestKeys[j]
+		if a.Route != b.Route {
+			return a.Route < b.Route
+		}
+		if a.Method != b.Method {
+			return a.Method < b.Method
+		}
+		return a.Status < b.Status
+	})
+
+	samples := make([]Sample, 0, len(requestKeys))
+	for _, key := range requestKeys {
+		samples = append(samples, Sample{
+			Name:   "http_requests_total",
+			Help:   "Total HTTP requests by route pattern, method and status.",
+			Type:   TypeCounter,
+			Labels: map[string]string{"method": key.Method, "route": key.Route, "status": key.Status},
+			Value:  float64(m.requests[key]),
+		})
+	}
+
+	routeKeys := make([]routeKey, 0, len(m.durations))
+	for key := range m.durations {
+		routeKeys = append(routeKeys, key)
+	}
+	sort.Slice(routeKeys, func(i, j int) bool {
+		if routeKeys[i].Route != routeKeys[j].Route {
+			return routeKeys[i].Route < routeKeys[j].Route
+		}
+		return routeKeys[i].Method < routeKeys[j].Method
+	})
+
+	const name = "http_request_duration_seconds"
+	const help = "HTTP request latency by route pattern and method."
+	for _, key := range routeKeys {
+		h := m.durations[key]
+		for i, bound := range defaultBuckets {
+			samples = append(samples, Sample{
+				Name:   name + "_bucket",
+				Help:   help,
+				Type:   TypeHistogram,
+				Labels: map[string]string{"method": key.Method, "route": key.Route, "le": formatValue(bound)},
+				Value:  float64(h.counts[i]),
+			})
+		}
+		samples = append(samples,
+			Sample{Name: name + "_bucket", Help: help, Type: TypeHistogram,
+				Labels: map[string]string{"method": key.Method, "route": key.Route, "le": "+Inf"}, Value: float64(h.count)},
+			Sample{Name: name + "_sum", Help: help, Type: TypeHistogram,
+				Labels: map[string]string{"method": key.Method, "route": key.Route}, Value: h.sum},
+			Sample{Name: name + "_count", Help: help, Type: TypeHistogram,
+				Labels: map[string]string{"method": key.Method, "route": key.Route}, Value: float64(h.count)},
+		)
+	}
+	return samples
+}
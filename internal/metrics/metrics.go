@@ -0,0 +1,164 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	TypeCounter   = "counter"
+	TypeGauge     = "gauge"
+	TypeHistogram = "histogram"
+)
+
+// Sample 是一条指标样本，同名样本必须使用相同的 Help 与 Type
+type Sample struct {
+	Name   string
+	Help   string
+	Type   string
+	Labels map[string]string
+	Value  float64
+}
+
+// Collector 在每次抓取时被调用，返回需要输出的样本
+type Collector func(ctx context.Context) ([]Sample, error)
+
+// Registry 汇总 HTTP 请求指标与各服务注册的 Collector，并以 Prometheus 文本格式输出
+type Registry struct {
+	logger     *log.Logger
+	http       *httpMetrics
+	mu         sync.Mutex
+	collectors []Collector
+}
+
+func NewRegistry(logger *log.Logger) *Registry {
+	registry := &Registry{
+		logger: logger,
+		http:   newHTTPMetrics(),
+	}
+	registry.Register(runtimeCollector(time.Now()))
+	return registry
+}
+
+// Register 追加一个 Collector
+func (r *Registry) Register(collector Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, collector)
+}
+
+// ServeHTTP 输出 /metrics，单个 Collector 出错只记录日志，不影响其他指标
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	collectors := append([]Collector(nil), r.collectors...)
+	r.mu.Unlock()
+
+	samples := r.http.samples()
+	for _, collector := range collectors {
+		collected, err := collector(req.Context())
+		if err != nil {
+			if r.logger != nil {
+				r.logger.Printf("metrics collector error: %v", err)
+			}
+			continue
+		}
+		samples = append(samples, collected...)
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if err := Write(w, samples); err != nil && r.logger != nil {
+		r.logger.Printf("metrics write error: %v", err)
+	}
+}
+
+// Write 按名称分组输出样本，每组只写一次 HELP/TYPE
+func Write(w io.Writer, samples []Sample) error {
+	order := make([]string, 0)
+	groups := make(map[string][]Sample)
+	for _, sample := range samples {
+		family := familyName(sample)
+		if _, ok := groups[family]; !ok {
+			order = append(order, family)
+		}
+		groups[family] = append(groups[family], sample)
+	}
+	sort.Strings(order)
+
+	for _, family := range order {
+		group := groups[family]
+		first := group[0]
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", family, escapeHelp(first.Help), family, first.Type); err != nil {
+			return err
+		}
+		for _, sample := range group {
+			if _, err := fmt.Fprintf(w, "%s%s %s\n", sample.Name, formatLabels(sample.Labels), formatValue(sample.Value)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func familyName(sample Sample) string {
+	// 直方图的 _bucket/_sum/_count 属于同一个指标族
+	if sample.Type == TypeHistogram {
+		for _, suffix := range []string{"_bucket", "_sum", "_count"} {
+			if name, ok := strings.CutSuffix(sample.Name, suffix); ok {
+				return name
+			}
+		}
+	}
+	return sample.Name
+}
+
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, key, escapeLabel(labels[key])))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+func escapeHelp(value string) string {
+	return helpEscaper.Replace(value)
+}
@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestMiddlewareLabelsByRoutePattern(t *testing.T) {
+	registry := NewRegistry(log.New(io.Discard, "", 0))
+
+	r := chi.NewRouter()
+	r.Use(registry.Middleware)
+	r.Get("/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	r.Method(http.MethodGet, "/metrics", registry)
+
+	for _, path := range []string{"/items/1", "/items/2", "/missing"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+
+	expected := []string{
+		"# TYPE http_requests_total counter",
+		`http_requests_total{method="GET",route="/items/{id}",status="418"} 2`,
+		`http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		"# TYPE http_request_duration_seconds histogram",
+		`http_request_duration_seconds_bucket{le="+Inf",method="GET",route="/items/{id}"} 2`,
+		`http_request_duration_seconds_count{method="GET",route="/items/{id}"} 2`,
+		"# TYPE go_goroutines gauge",
+	}
+	for _, line := range expected {
+		if !strings.Contains(body, line) {
+			t.Fatalf("missing %q in output:\n%s", line, body)
+		}
+	}
+	if strings.Count(body, "# TYPE http_request_duration_seconds ") != 1 {
+		t.Fatalf("histogram family should be declared once:\n%s", body)
+	}
+}
+
+func TestWriteEscapesLabels(t *testing.T) {
+	var sb strings.Builder
+	err := Write(&sb, []Sample{{
+		Name:   "example",
+		Help:   "line\nbreak",
+		Type:   TypeGauge,
+		Labels: map[string]string{"path": `a"b\c`},
+		Value:  1.5,
+	}})
+	if err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	want := "# HELP example line\\nbreak\n# TYPE example gauge\nexample{path=\"a\\\"b\\\\c\"} 1.5\n"
+	if sb.String() != want {
+		t.Fatalf("unexpected output:\n%q\nwant\n%q", sb.String(), want)
+	}
+}
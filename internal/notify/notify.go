@@ -0,0 +1,33 @@
+package notify
+
+import (
+	"context"
+	"log"
+)
+
+// Notification 是一条待发送的提醒消息
+type Notification struct {
+	Recipient string         `json:"recipient"`
+	Subject   string         `json:"subject"`
+	Body      string         `json:"body"`
+	Data      map[string]any `json:"data,omitempty"`
+}
+
+// Notifier 抽象不同的投递渠道（日志、webhook、邮件）
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// LogNotifier 只把提醒写入日志，适合本地开发
+type LogNotifier struct {
+	logger *log.Logger
+}
+
+func NewLogNotifier(logger *log.Logger) *LogNotifier {
+	return &LogNotifier{logger: logger}
+}
+
+func (n *LogNotifier) Notify(ctx context.Context, msg Notification) error {
+	n.logger.Printf("notify recipient=%q subject=%q body=%q", msg.Recipient, msg.Subject, msg.Body)
+	return nil
+}
@@ -0,0 +1,124 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// SMTPConfig 描述 SMTP 服务器及默认收件人
+type SMTPConfig struct {
+	Addr      string
+	From      string
+	Username  string
+	Password  string
+	DefaultTo string
+}
+
+// SMTPNotifier 通过 SMTP 发送纯文本邮件
+type SMTPNotifier struct {
+	cfg SMTPConfig
+}
+
+func NewSMTPNotifier(cfg SMTPConfig) *SMTPNotifier {
+	return &SMTPNotifier{cfg: cfg}
+}
+
+func (n *SMTPNotifier) Notify(ctx context.Context, msg Notification) error {
+	to := msg.Recipient
+	if to == "" {
+		to = n.cfg.DefaultTo
+	}
+	if to == "" {
+		return errors.New("smtp: no recipient")
+	}
+	return n.SendRaw(ctx, to, BuildMessage(n.cfg.From, to, msg.Subject, msg.Body))
+}
+
+// SendRaw 投递已组装好的邮件，mail 包发送 HTML 邮件时复用
+func (n *SMTPNotifier) SendRaw(ctx context.Context, to string, message []byte) error {
+	client, err := n.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if n.cfg.Username != "" {
+		if ok, _ := client.Extension("AUTH"); ok {
+			host, _, _ := net.SplitHostPort(n.cfg.Addr)
+			if err := client.Auth(smtp.PlainAuth("", n.cfg.Username, n.cfg.Password, host)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := client.Mail(n.cfg.From); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+
+	wc, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := wc.Write(message); err != nil {
+		_ = wc.Close()
+		return err
+	}
+	if err := wc.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+func (n *SMTPNotifier) dial(ctx context.Context) (*smtp.Client, error) {
+	// net/smtp 不支持 context，这里自行拨号并用截止时间约束整个会话
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", n.cfg.Addr)
+	if err != nil {
+		return nil, err
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(30 * time.Second)
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	host, _, err := net.SplitHostPort(n.cfg.Addr)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// BuildMessage 组装最简单的 RFC 5322 文本邮件
+func BuildMessage(from, to, subject, body string) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", to)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("\r\n")
+	// 正文中的换行与点号转义由 smtp 的 DotWriter 负责
+	buf.WriteString(body)
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
@@ -0,0 +1,55 @@
+package notify
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go_test/internal/notify/smtptest"
+)
+
+func TestSMTPNotifierDeliversToFakeServer(t *testing.T) {
+	server := smtptest.NewServer(t)
+	notifier := NewSMTPNotifier(SMTPConfig{
+		Addr:      server.Addr(),
+		From:      "todo@example.com",
+		DefaultTo: "fallback@example.com",
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := notifier.Notify(ctx, Notification{
+		Recipient: "alice@example.com",
+		Subject:   "Reminder: ship v1.2",
+		Body:      "line one\nline two",
+	})
+	if err != nil {
+		t.Fatalf("notify: %v", err)
+	}
+
+	select {
+	case msg := <-server.Messages:
+		if msg.From != "todo@example.com" {
+			t.Fatalf("unexpected from: %q", msg.From)
+		}
+		if len(msg.To) != 1 || msg.To[0] != "alice@example.com" {
+			t.Fatalf("unexpected recipients: %#v", msg.To)
+		}
+		if !strings.Contains(msg.Data, "Subject: Reminder: ship v1.2") {
+			t.Fatalf("missing subject header: %q", msg.Data)
+		}
+		if !strings.Contains(msg.Data, "line one\nline two") {
+			t.Fatalf("missing body: %q", msg.Data)
+		}
+	case <-ctx.Done():
+		t.Fatal("fake server did not receive a message")
+	}
+}
+
+func TestSMTPNotifierRequiresRecipient(t *testing.T) {
+	notifier := NewSMTPNotifier(SMTPConfig{Addr: "127.0.0.1:1", From: "todo@example.com"})
+	if err := notifier.Notify(context.Background(), Notification{Subject: "x"}); err == nil {
+		t.Fatal("expected error without recipient")
+	}
+}
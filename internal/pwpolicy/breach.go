@@ -0,0 +1,57 @@
+package pwpolicy
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BreachList 按 k-anonymity 前缀文件查询泄露密码：目录中每个文件以 SHA-1 摘要的前 5 位十六进制命名
+// （如 21BD1.txt），每行是其余 35 位摘要与出现次数 "SUFFIX:COUNT"，与 Have I Been Pwned 的
+// range 接口及其下载工具的输出一致。查询时只读取对应前缀的文件，不需要把整份列表载入内存
+type BreachList struct {
+	dir string
+}
+
+func NewBreachList(dir string) (*BreachList, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("pwpolicy: %s is not a directory", dir)
+	}
+	return &BreachList{dir: dir}, nil
+}
+
+// Contains 报告密码是否出现在泄露列表中；缺少对应前缀文件视为未泄露
+func (b *BreachList) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	digest := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := digest[:5], digest[5:]
+
+	file, err := os.Open(filepath.Join(b.dir, prefix+".txt"))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		hash, count, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		// 补齐响应长度的填充行出现次数为 0，不算泄露
+		if strings.EqualFold(hash, suffix) && strings.TrimLeft(count, "0") != "" {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
@@ -0,0 +1,131 @@
+package pwpolicy
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	CodeTooShort         = "password_too_short"
+	CodeTooLong          = "password_too_long"
+	CodeCharacterClasses = "password_character_classes"
+	CodePersonalInfo     = "password_contains_personal_info"
+	CodeTooWeak          = "password_too_weak"
+	CodeBreached         = "password_breached"
+)
+
+// maxBytes 是 bcrypt 能处理的最大长度，超出部分会被拒绝而不是截断
+const maxBytes = 72
+
+// Policy 描述密码规则，零值字段表示不检查对应项
+type Policy struct {
+	MinLength int
+	MaxLength int
+	// MinClasses 要求至少包含几类字符：小写字母、大写字母、数字、其他符号
+	MinClasses int
+	// MinStrength 是 Strength 的最低分（0-4）
+	MinStrength int
+	// DisallowPersonal 禁止密码包含邮箱、邮箱用户名或姓名（忽略大小写，少于 3 个字符的部分不检查）
+	DisallowPersonal bool
+	Breached         *BreachList
+}
+
+// Violation 是一条未满足的规则，Code 供客户端识别，Message 可直接展示
+type Violation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Check 返回密码违反的全部规则；error 只来自泄露列表读取失败，此时其余检查结果仍然有效
+func (p Policy) Check(password string, personal ...string) ([]Violation, error) {
+	var violations []Violation
+	length := utf8.RuneCountInString(password)
+	if p.MinLength > 0 && length < p.MinLength {
+		violations = append(violations, Violation{CodeTooShort, fmt.Sprintf("password must be at least %d characters", p.MinLength)})
+	}
+	if (p.MaxLength > 0 && length > p.MaxLength) || len(password) > maxBytes {
+		limit := p.MaxLength
+		if limit <= 0 || limit > maxBytes {
+			limit = maxBytes
+		}
+		violations = append(violations, Violation{CodeTooLong, fmt.Sprintf("password must be at most %d characters", limit)})
+	}
+	if p.MinClasses > 0 && characterClasses(password) < p.MinClasses {
+		violations = append(violations, Violation{CodeCharacterClasses, fmt.Sprintf("password must contain at least %d of: lowercase letters, uppercase letters, digits, symbols", p.MinClasses)})
+	}
+
+	inputs := personalInputs(personal)
+	if p.DisallowPersonal && containsAny(strings.ToLower(password), inputs) {
+		violations = append(violations, Violation{CodePersonalInfo, "password must not contain your email or name"})
+	}
+	if p.MinStrength > 0 && Strength(password, personal...) < p.MinStrength {
+		violations = append(violations, Violation{CodeTooWeak, "password is too easy to guess"})
+	}
+
+	if p.Breached != nil {
+		breached, err := p.Breached.Contains(password)
+		if err != nil {
+			return violations, err
+		}
+		if breached {
+			violations = append(violations, Violation{CodeBreached, "password has appeared in a data breach, choose a different one"})
+		}
+	}
+	return violations, nil
+}
+
+func characterClasses(password string) int {
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	count := 0
+	for _, present := range []bool{lower, upper, digit, symbol} {
+		if present {
+			count++
+		}
+	}
+	return count
+}
+
+// personalInputs 把邮箱拆成完整地址与用户名，姓名拆成单词，统一小写
+func personalInputs(values []string) []string {
+	var inputs []string
+	for _, value := range values {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if local, _, ok := strings.Cut(value, "@"); ok {
+			inputs = append(inputs, value, local)
+			continue
+		}
+		inputs = append(inputs, value)
+		inputs = append(inputs, strings.Fields(value)...)
+	}
+
+	filtered := inputs[:0]
+	for _, input := range inputs {
+		if utf8.RuneCountInString(input) >= 3 {
+			filtered = append(filtered, input)
+		}
+	}
+	return filtered
+}
+
+func containsAny(value string, parts []string) bool {
+	for _, part := range parts {
+		if strings.Contains(value, part) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package pwpolicy
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func codes(violations []Violation) string {
+	list := make([]string, len(violations))
+	for i, v := range violations {
+		list[i] = v.Code
+	}
+	return strings.Join(list, ",")
+}
+
+func TestPolicyCheck(t *testing.T) {
+	policy := Policy{MinLength: 8, MaxLength: 64, MinClasses: 3, MinStrength: 2, DisallowPersonal: true}
+	cases := []struct {
+		password string
+		want     string
+	}{
+		{"Xk9#mP2$vL", ""},
+		{"Ab1", CodeTooShort + "," + CodeTooWeak},
+		{"lowercaseonly-but-long", CodeCharacterClasses},
+		{"Demo-Xk9#mP2", CodePersonalInfo},
+		{"Zhang Wei 9!x", CodePersonalInfo + "," + CodeTooWeak},
+		{"Password123", CodeTooWeak},
+		{"P@ssw0rd2024", CodeTooWeak},
+		{strings.Repeat("Ab1!", 20), CodeTooLong},
+	}
+	for _, tc := range cases {
+		violations, err := policy.Check(tc.password, "demo@example.com", "Zhang Wei")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := codes(violations); got != tc.want {
+			t.Fatalf("Check(%q) = %q, want %q", tc.password, got, tc.want)
+		}
+	}
+
+	// bcrypt 的 72 字节上限独立于 MaxLength
+	violations, _ := Policy{}.Check(strings.Repeat("密", 25))
+	if codes(violations) != CodeTooLong {
+		t.Fatalf("multi-byte password over bcrypt limit: %q", codes(violations))
+	}
+}
+
+func TestStrength(t *testing.T) {
+	cases := []struct {
+		password string
+		want     int
+	}{
+		{"password", 0},
+		{"123456789", 0},
+		{"qwerty123", 0},
+		{"aaaaaaaaaaaa", 0},
+		{"abcdefgh", 0},
+		{"p4ssw0rd", 0},
+		{"monkey1987", 1},
+		{"Xk9#mP2$vL", 4},
+		{"correct horse battery staple", 4},
+	}
+	for _, tc := range cases {
+		if got := Strength(tc.password); got != tc.want {
+			t.Fatalf("Strength(%q) = %d, want %d", tc.password, got, tc.want)
+		}
+	}
+	if Strength("alice2024!") <= Strength("alice2024!", "alice@example.com") {
+		t.Fatal("expected personal inputs to lower the score")
+	}
+}
+
+func TestBreachList(t *testing.T) {
+	dir := t.TempDir()
+	sum := sha1.Sum([]byte("hunter2"))
+	digest := strings.ToUpper(hex.EncodeToString(sum[:]))
+	other := sha1.Sum([]byte("padded"))
+	padded := strings.ToUpper(hex.EncodeToString(other[:]))
+
+	write := func(prefix, content string) {
+		if err := os.WriteFile(filepath.Join(dir, prefix+".txt"), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(digest[:5], "0000000000000000000000000000000000A:3\r\n"+digest[5:]+":17\r\n")
+	write(padded[:5], padded[5:]+":0\r\n")
+
+	list, err := NewBreachList(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for password, want := range map[string]bool{"hunter2": true, "padded": false, "not-in-list": false} {
+		got, err := list.Contains(password)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Fatalf("Contains(%q) = %v, want %v", password, got, want)
+		}
+	}
+
+	violations, err := Policy{Breached: list}.Check("hunter2")
+	if err != nil || codes(violations) != CodeBreached {
+		t.Fatalf("breached password: %q, %v", codes(violations), err)
+	}
+	if _, err := NewBreachList(filepath.Join(dir, "missing")); err == nil {
+		t.Fatal("expected error for missing directory")
+	}
+}
@@ -0,0 +1,205 @@
+package pwpolicy
+
+import (
+	_ "embed"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// common.txt 按常见程度排序，行号即猜测时的排名
+//
+//go:embed common.txt
+var commonList string
+
+var commonRanks = func() map[string]int {
+	ranks := map[string]int{}
+	for i, word := range strings.Fields(commonList) {
+		if _, ok := ranks[word]; !ok {
+			ranks[word] = i + 1
+		}
+	}
+	return ranks
+}()
+
+var keyboardRows = []string{"1234567890", "qwertyuiop", "asdfghjkl", "zxcvbnm"}
+
+var leet = strings.NewReplacer("4", "a", "@", "a", "3", "e", "1", "i", "!", "i", "0", "o", "$", "s", "5", "s", "7", "t")
+
+// Strength 参考 zxcvbn 的思路估计猜测次数并换算为 0-4 分：把密码切分为常见词、个人信息、重复、
+// 连续序列、键盘连线、年份等片段，各片段的猜测次数相乘；无法匹配的字符按所属字符集计算。
+// personal 中的邮箱与姓名按排名第一的常见词处理。切分采用贪心的最长匹配，比 zxcvbn 的全局最优搜索粗糙，但足以区分明显的弱密码
+func Strength(password string, personal ...string) int {
+	guesses := log10Guesses(password, personalInputs(personal))
+	switch {
+	case guesses < 3:
+		return 0
+	case guesses < 6:
+		return 1
+	case guesses < 8:
+		return 2
+	case guesses < 10:
+		return 3
+	default:
+		return 4
+	}
+}
+
+type segment struct {
+	length  int
+	guesses float64
+}
+
+func log10Guesses(password string, personal []string) float64 {
+	original := []rune(password)
+	lower := []rune(strings.ToLower(password))
+	// leet 的替换都是单字符，unleet 与 lower 按位置一一对应
+	unleet := []rune(leet.Replace(string(lower)))
+
+	words := make(map[string]int, len(personal))
+	for _, value := range personal {
+		words[value] = 1
+	}
+
+	var total float64
+	for i := 0; i < len(lower); {
+		best := segment{length: 1, guesses: cardinality(original[i])}
+		for _, candidate := range []segment{
+			matchWord(original, lower, unleet, i, words),
+			matchRepeat(lower, i),
+			matchSequence(lower, i),
+			matchKeyboard(lower, i),
+			matchYear(lower, i),
+		} {
+			if candidate.length > best.length || (candidate.length == best.length && candidate.length > 1 && candidate.guesses < best.guesses) {
+				best = candidate
+			}
+		}
+		total += math.Log10(best.guesses)
+		i += best.length
+	}
+	return total
+}
+
+func cardinality(r rune) float64 {
+	switch {
+	case r >= '0' && r <= '9':
+		return 10
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		return 26
+	case r < unicode.MaxASCII:
+		return 33
+	default:
+		return 100
+	}
+}
+
+func matchWord(original, lower, unleet []rune, start int, personal map[string]int) segment {
+	var best segment
+	for end := len(lower); end-start >= 3; end-- {
+		for _, candidate := range [][]rune{lower[start:end], unleet[start:end]} {
+			word := string(candidate)
+			rank, ok := personal[word]
+			if !ok {
+				rank, ok = commonRanks[word]
+			}
+			if !ok {
+				continue
+			}
+
+			guesses := float64(rank)
+			for _, r := range original[start:end] {
+				if unicode.IsUpper(r) {
+					guesses *= 2
+					break
+				}
+			}
+			if string(lower[start:end]) != word {
+				guesses *= 2
+			}
+			if best.length == 0 || guesses < best.guesses {
+				best = segment{length: end - start, guesses: guesses}
+			}
+		}
+		if best.length > 0 {
+			return best
+		}
+	}
+	return segment{}
+}
+
+func matchRepeat(lower []rune, start int) segment {
+	end := start + 1
+	for end < len(lower) && lower[end] == lower[start] {
+		end++
+	}
+	if end-start < 3 {
+		return segment{}
+	}
+	return segment{length: end - start, guesses: cardinality(lower[start]) * float64(end-start)}
+}
+
+func matchSequence(lower []rune, start int) segment {
+	if start+2 >= len(lower) {
+		return segment{}
+	}
+	delta := lower[start+1] - lower[start]
+	if delta != 1 && delta != -1 {
+		return segment{}
+	}
+	end := start + 1
+	for end < len(lower) && lower[end]-lower[end-1] == delta {
+		end++
+	}
+	if end-start < 3 {
+		return segment{}
+	}
+
+	guesses := cardinality(lower[start])
+	if strings.ContainsRune("a1z09", lower[start]) {
+		guesses = 4
+	}
+	if delta < 0 {
+		guesses *= 2
+	}
+	return segment{length: end - start, guesses: guesses * float64(end-start)}
+}
+
+func matchKeyboard(lower []rune, start int) segment {
+	var best segment
+	for _, row := range keyboardRows {
+		for _, line := range []string{row, reverse(row)} {
+			offset := strings.IndexRune(line, lower[start])
+			if offset < 0 {
+				continue
+			}
+			length := 1
+			for start+length < len(lower) && offset+length < len(line) && rune(line[offset+length]) == lower[start+length] {
+				length++
+			}
+			if length >= 3 && length > best.length {
+				best = segment{length: length, guesses: 40 * float64(length)}
+			}
+		}
+	}
+	return best
+}
+
+func matchYear(lower []rune, start int) segment {
+	if start+4 > len(lower) {
+		return segment{}
+	}
+	year := string(lower[start : start+4])
+	if (strings.HasPrefix(year, "19") || strings.HasPrefix(year, "20")) && strings.Trim(year, "0123456789") == "" {
+		return segment{length: 4, guesses: 120}
+	}
+	return segment{}
+}
+
+func reverse(value string) string {
+	runes := []rune(value)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes)
+}
@@ -0,0 +1,40 @@
+package stats
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// summaryCache 是进程内的 TTL 缓存，ttl 为 0 时不缓存
+type summaryCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	value   Summary
+	expires time.Time
+}
+
+func newSummaryCache(ttl time.Duration) *summaryCache {
+	return &summaryCache{ttl: ttl}
+}
+
+func (c *summaryCache) get(ctx context.Context, load func(context.Context) (Summary, error)) (Summary, error) {
+	if c.ttl <= 0 {
+		return load(ctx)
+	}
+
+	// 持锁加载，缓存过期时同一时刻只有一个请求访问数据库
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if time.Now().Before(c.expires) {
+		return c.value, nil
+	}
+
+	value, err := load(ctx)
+	if err != nil {
+		return Summary{}, err
+	}
+	c.value = value
+	c.expires = time.Now().Add(c.ttl)
+	return value, nil
+}
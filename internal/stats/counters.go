@@ -0,0 +1,142 @@
+package stats
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// queryer 同时由 *sql.DB 与 *sql.Tx 实现
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func exactSummary(ctx context.Context, q queryer) (Summary, error) {
+	// 全表扫描得到的精确计数，只用于兜底与对账
+	var summary Summary
+	row := q.QueryRowContext(ctx, `
+		SELECT COUNT(*),
+			COUNT(*) FILTER (WHERE done),
+			COUNT(*) FILTER (WHERE archived_at IS NOT NULL)
+		FROM todos
+	`)
+	if err := row.Scan(&summary.Total, &summary.Done, &summary.Archived); err != nil {
+		return Summary{}, err
+	}
+	return summary, nil
+}
+
+func isUndefinedTable(err error) bool {
+	// 42P01 是 undefined_table：升级顺序要求 stats-api 先于分片迁移上线
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "42P01"
+}
+
+// Reconcile 重新计算精确计数并修正计数分片，返回修正前的偏差（分片之和减精确值）。
+// 先锁住全部分片：已提交事务的增量都已写入分片，尚未写入的事务会等到对账提交后再累加，
+// 因此对账期间的并发写入不会造成新的偏差。修正时精确值写入 0 号分片，其余分片清零
+func (s *Store) Reconcile(ctx context.Context) (Summary, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Summary{}, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO todo_counter_shards (shard)
+		VALUES (0)
+		ON CONFLICT (shard) DO NOTHING
+	`); err != nil {
+		if isUndefinedTable(err) {
+			// 分片迁移尚未执行，没有需要对账的计数
+			return Summary{}, nil
+		}
+		return Summary{}, err
+	}
+
+	var stored Summary
+	row := tx.QueryRowContext(ctx, `
+		WITH locked AS (
+			SELECT total, done, archived
+			FROM todo_counter_shards
+			ORDER BY shard
+			FOR UPDATE
+		)
+		SELECT COALESCE(SUM(total), 0), COALESCE(SUM(done), 0), COALESCE(SUM(archived), 0)
+		FROM locked
+	`)
+	if err := row.Scan(&stored.Total, &stored.Done, &stored.Archived); err != nil {
+		return Summary{}, err
+	}
+
+	exact, err := exactSummary(ctx, tx)
+	if err != nil {
+		return Summary{}, err
+	}
+
+	drift := Summary{
+		Total:    stored.Total - exact.Total,
+		Done:     stored.Done - exact.Done,
+		Archived: stored.Archived - exact.Archived,
+	}
+	if drift != (Summary{}) {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE todo_counter_shards
+			SET total = CASE WHEN shard = 0 THEN $1 ELSE 0 END,
+				done = CASE WHEN shard = 0 THEN $2 ELSE 0 END,
+				archived = CASE WHEN shard = 0 THEN $3 ELSE 0 END,
+				updated_at = NOW()
+		`, exact.Total, exact.Done, exact.Archived); err != nil {
+			return Summary{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Summary{}, err
+	}
+	return drift, nil
+}
+
+// Reconciler 周期性对账计数表
+type Reconciler struct {
+	store    *Store
+	logger   *log.Logger
+	interval time.Duration
+}
+
+func NewReconciler(store *Store, logger *log.Logger, interval time.Duration) *Reconciler {
+	return &Reconciler{
+		store:    store,
+		logger:   logger,
+		interval: interval,
+	}
+}
+
+func (r *Reconciler) Run(ctx context.Context) {
+	// 按固定间隔对账，直到 ctx 被取消
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		drift, err := r.store.Reconcile(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				r.logger.Printf("stats reconcile error: %v", err)
+			}
+			continue
+		}
+		if drift != (Summary{}) {
+			r.logger.Printf("stats reconcile corrected drift: total=%d done=%d archived=%d", drift.Total, drift.Done, drift.Archived)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package stats
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const csvContentType = "text/csv"
+
+func wantsCSV(r *http.Request) bool {
+	// Accept 中 text/csv 的权重不低于 application/json 时返回 CSV，未出现 text/csv 时仍返回 JSON
+	csvQ, jsonQ := -1.0, -1.0
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+		switch mediaType {
+		case csvContentType:
+			csvQ = q
+		case "application/json":
+			jsonQ = q
+		}
+	}
+	return csvQ > 0 && csvQ >= jsonQ
+}
+
+// csvTable 由列不固定的结果（如 /stats/query）实现，直接给出表头与各行
+type csvTable interface {
+	csvRecords() ([]string, [][]string)
+}
+
+func encodeCSV(w io.Writer, rows any) error {
+	// rows 是结构体或结构体切片，表头取 json 标签名，与 JSON 响应的字段保持一致
+	if table, ok := rows.(csvTable); ok {
+		header, records := table.csvRecords()
+		writer := csv.NewWriter(w)
+		writer.Write(header)
+		writer.WriteAll(records)
+		return writer.Error()
+	}
+
+	value := reflect.ValueOf(rows)
+	if value.Kind() != reflect.Slice {
+		slice := reflect.MakeSlice(reflect.SliceOf(value.Type()), 1, 1)
+		slice.Index(0).Set(value)
+		value = slice
+	}
+	elemType := value.Type().Elem()
+	if elemType.Kind() != reflect.Struct {
+		return fmt.Errorf("csv: unsupported row type %s", elemType)
+	}
+
+	var fields []int
+	var header []string
+	for i := 0; i < elemType.NumField(); i++ {
+		field := elemType.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, i)
+		header = append(header, name)
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	record := make([]string, len(fields))
+	for i := 0; i < value.Len(); i++ {
+		row := value.Index(i)
+		for j, index := range fields {
+			record[j] = formatCSVValue(row.Field(index))
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func formatCSVValue(value reflect.Value) string {
+	// 空指针输出空单元格，时间统一为 RFC3339
+	if value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return ""
+		}
+		value = value.Elem()
+	}
+	if t, ok := value.Interface().(time.Time); ok {
+		return t.Format(time.RFC3339)
+	}
+	switch value.Kind() {
+	case reflect.String:
+		return value.String()
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(value.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(value.Float(), 'f', -1, 64)
+	default:
+		return fmt.Sprint(value.Interface())
+	}
+}
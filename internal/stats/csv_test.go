@@ -0,0 +1,64 @@
+package stats
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWantsCSV(t *testing.T) {
+	cases := []struct {
+		accept string
+		want   bool
+	}{
+		{"", false},
+		{"application/json", false},
+		{"text/csv", true},
+		{"text/csv;q=0.5, application/json", false},
+		{"application/json;q=0.5, text/csv", true},
+		{"text/csv;q=0", false},
+		{"*/*", false},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest("GET", "/stats", nil)
+		if tc.accept != "" {
+			req.Header.Set("Accept", tc.accept)
+		}
+		if got := wantsCSV(req); got != tc.want {
+			t.Fatalf("wantsCSV(%q) = %v, want %v", tc.accept, got, tc.want)
+		}
+	}
+}
+
+func TestEncodeCSV(t *testing.T) {
+	avg := 1.5
+	rows := []UserBreakdown{
+		{UserID: 1, Email: "a@example.com", Total: 3, Done: 2, CompletionRate: 0.67, AvgHoursToComplete: &avg},
+		{UserID: 2, Email: "b,c@example.com"},
+	}
+
+	var sb strings.Builder
+	if err := encodeCSV(&sb, rows); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	want := "user_id,email,total,done,overdue,completion_rate,avg_hours_to_complete\n" +
+		"1,a@example.com,3,2,0,0.67,1.5\n" +
+		"2,\"b,c@example.com\",0,0,0,0,\n"
+	if sb.String() != want {
+		t.Fatalf("unexpected csv:\n%s", sb.String())
+	}
+}
+
+func TestEncodeCSVSingleStruct(t *testing.T) {
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	var sb strings.Builder
+	if err := encodeCSV(&sb, Bucket{Start: start, Created: 4, Completed: 1}); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	want := "start,created,completed\n2024-03-01T00:00:00Z,4,1\n"
+	if sb.String() != want {
+		t.Fatalf("unexpected csv:\n%s", sb.String())
+	}
+}
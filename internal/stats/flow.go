@@ -0,0 +1,117 @@
+package stats
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"time"
+)
+
+// FlowQuery 描述按天还原历史状态的范围，From/To 为所选时区内的日期（两端都包含）
+type FlowQuery struct {
+	From     time.Time
+	To       time.Time
+	Location *time.Location
+	// List 为 nil 时统计全部 todo
+	List *string
+}
+
+// FlowDay 是某一天结束时（或当天截至现在）各状态的 todo 数量，即累积流图的一列
+type FlowDay struct {
+	Date       string `json:"date"`
+	Open       int64  `json:"open"`
+	InProgress int64  `json:"in_progress"`
+	Blocked    int64  `json:"blocked"`
+	Snoozed    int64  `json:"snoozed"`
+	Done       int64  `json:"done"`
+	Cancelled  int64  `json:"cancelled"`
+}
+
+// Remaining 是仍未结束（既未完成也未取消）的数量
+func (d FlowDay) Remaining() int64 {
+	return d.Open + d.InProgress + d.Blocked + d.Snoozed
+}
+
+// BurndownPoint 是燃尽图的一天：实际剩余数与理想线
+type BurndownPoint struct {
+	Date      string  `json:"date"`
+	Remaining int64   `json:"remaining"`
+	Ideal     float64 `json:"ideal"`
+}
+
+func (s *Store) DailyFlow(ctx context.Context, query FlowQuery) ([]FlowDay, error) {
+	// 对每一天取该日结束时刻之前每个 todo 的最后一条历史记录，得到当天的状态与所属清单；
+	// 清单过滤基于当时的清单，已删除的 todo 从删除当天起不再计入
+	return read(ctx, s, func(db *sql.DB) ([]FlowDay, error) {
+		rows, err := db.QueryContext(ctx, `
+			WITH days AS (
+				SELECT d::date AS day, (d::date + 1)::timestamp AT TIME ZONE $3 AS day_end
+				FROM generate_series($1::date, $2::date, INTERVAL '1 day') AS d
+			),
+			states AS (
+				SELECT days.day, s.status
+				FROM days
+				CROSS JOIN LATERAL (
+					SELECT DISTINCT ON (h.todo_id) h.status, h.list_name
+					FROM todo_status_history h
+					WHERE h.changed_at < days.day_end
+					ORDER BY h.todo_id, h.changed_at DESC, h.id DESC
+				) s
+				WHERE s.status <> 'deleted'
+					AND ($4::text IS NULL OR s.list_name = $4)
+			)
+			SELECT days.day,
+				COUNT(*) FILTER (WHERE st.status = 'open'),
+				COUNT(*) FILTER (WHERE st.status = 'in_progress'),
+				COUNT(*) FILTER (WHERE st.status = 'blocked'),
+				COUNT(*) FILTER (WHERE st.status = 'snoozed'),
+				COUNT(*) FILTER (WHERE st.status = 'done'),
+				COUNT(*) FILTER (WHERE st.status = 'cancelled')
+			FROM days
+			LEFT JOIN states st ON st.day = days.day
+			GROUP BY days.day
+			ORDER BY days.day
+		`, query.From.Format(time.DateOnly), query.To.Format(time.DateOnly), query.Location.String(), query.List)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		days := []FlowDay{}
+		for rows.Next() {
+			var day FlowDay
+			var date time.Time
+			if err := rows.Scan(&date, &day.Open, &day.InProgress, &day.Blocked, &day.Snoozed, &day.Done, &day.Cancelled); err != nil {
+				return nil, err
+			}
+			day.Date = date.Format(time.DateOnly)
+			days = append(days, day)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return days, nil
+	})
+}
+
+func burndown(days []FlowDay) []BurndownPoint {
+	// 理想线从首日的剩余数匀速下降到最后一天为 0；只有一天时理想值即为 0
+	points := make([]BurndownPoint, 0, len(days))
+	if len(days) == 0 {
+		return points
+	}
+	start := float64(days[0].Remaining())
+	steps := float64(len(days) - 1)
+	for i, day := range days {
+		ideal := 0.0
+		if steps > 0 {
+			ideal = math.Round(start*(1-float64(i)/steps)*100) / 100
+		}
+		points = append(points, BurndownPoint{
+			Date:      day.Date,
+			Remaining: day.Remaining(),
+			Ideal:     ideal,
+		})
+	}
+	return points
+}
@@ -1,38 +1,111 @@
 package stats
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
+	"go_test/internal/auth"
+	"go_test/internal/metrics"
+
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
 
 type Handler struct {
-	store  *Store
-	logger *log.Logger
+	store   *Store
+	logger  *log.Logger
+	metrics *metrics.Registry
+	auth    auth.Authenticator
+	cache   *summaryCache
+	weights map[string]int64
+
+	queryTimeout time.Duration
+	queryMaxCost float64
 }
 
 func NewHandler(store *Store, logger *log.Logger) *Handler {
+	registry := metrics.NewRegistry(logger)
+	if store != nil {
+		registry.Register(metrics.DBStatsCollector(store.db))
+		registry.Register(store.collectMetrics)
+	}
 	return &Handler{
-		store:  store,
-		logger: logger,
+		store:   store,
+		logger:  logger,
+		metrics: registry,
+		cache:   newSummaryCache(0),
+		weights: DefaultPriorityWeights,
+
+		queryTimeout: 5 * time.Second,
+		queryMaxCost: 100000,
 	}
 }
 
+// WithQueryLimits 设置 /stats/query 的语句超时与 EXPLAIN 估算代价上限
+func (h *Handler) WithQueryLimits(timeout time.Duration, maxCost float64) *Handler {
+	h.queryTimeout = timeout
+	h.queryMaxCost = maxCost
+	return h
+}
+
+// WithPriorityWeights 设置排行榜按积分排名时各优先级的权重
+func (h *Handler) WithPriorityWeights(weights map[string]int64) *Handler {
+	h.weights = weights
+	return h
+}
+
+// WithCacheTTL 为 /stats 启用进程内缓存，并作为 Cache-Control 的 max-age
+func (h *Handler) WithCacheTTL(ttl time.Duration) *Handler {
+	h.cache = newSummaryCache(ttl)
+	return h
+}
+
+// WithAuthenticator 启用需要登录的个人统计与管理员统计接口
+func (h *Handler) WithAuthenticator(authenticator auth.Authenticator) *Handler {
+	h.auth = authenticator
+	return h
+}
+
 func (h *Handler) Routes() http.Handler {
 	// 注册路由与中间件
 	r := chi.NewRouter()
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
+	r.Use(h.metrics.Middleware)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.Timeout(30 * time.Second))
 
 	r.Get("/health", h.handleHealth)
+	r.Method(http.MethodGet, "/metrics", h.metrics)
 	r.Get("/stats", h.handleStats)
+	r.Get("/stats/timeseries", h.handleTimeseries)
+	r.Get("/stats/burndown", h.handleBurndown)
+	r.Get("/stats/flow", h.handleFlow)
+
+	if h.auth != nil {
+		r.Group(func(r chi.Router) {
+			r.Use(auth.Middleware(h.auth))
+			r.Use(auth.Require)
+			r.Get("/stats/me", h.handleMyStats)
+			r.Get("/stats/me/lists", h.handleMyListStats)
+			r.Get("/stats/leaderboard", h.handleLeaderboard)
+			r.Get("/stats/leaderboard/me", h.handleMyRank)
+
+			r.With(auth.RequireAdmin).Get("/stats/by-user", h.handleStatsByUser)
+			r.With(auth.RequireAdmin).Get("/stats/users", h.handleUserStats)
+			r.With(auth.RequireAdmin).Post("/stats/query", h.handleQuery)
+		})
+	}
 
 	return r
 }
@@ -43,13 +116,293 @@ func (h *Handler) handleHealth(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) handleStats(w http.ResponseWriter, r *http.Request) {
-	// 统计汇总
-	summary, err := h.store.Summary(r.Context())
+	// 统计汇总：优先读缓存，并支持 ETag 条件请求
+	summary, err := h.cache.get(r.Context(), h.store.Summary)
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to load stats")
+		return
+	}
+	if wantsCSV(r) {
+		h.respond(w, r, summary, summary)
+		return
+	}
+	w.Header().Set("Vary", "Accept")
+	h.writeCachedJSON(w, r, summary, summary.counts(), h.cache.ttl)
+}
+
+func (h *Handler) handleTimeseries(w http.ResponseWriter, r *http.Request) {
+	// 时间序列统计：?from=&to=&interval=day|week|month&tz=
+	query, err := parseTimeseriesQuery(r, time.Now())
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	buckets, err := h.store.Timeseries(r.Context(), query)
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to load timeseries")
+		return
+	}
+
+	h.respond(w, r, map[string]any{
+		"from":     query.From.In(query.Location),
+		"to":       query.To.In(query.Location),
+		"interval": query.Interval,
+		"tz":       query.Location.String(),
+		"buckets":  buckets,
+	}, buckets)
+}
+
+func (h *Handler) handleBurndown(w http.ResponseWriter, r *http.Request) {
+	// 燃尽图：?from=&to=&list=&tz=，每天的剩余未完成数与理想线
+	query, err := parseFlowQuery(r, time.Now())
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	days, err := h.store.DailyFlow(r.Context(), query)
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to load burndown")
+		return
+	}
+	points := burndown(days)
+
+	h.respond(w, r, map[string]any{
+		"from":   query.From.Format(time.DateOnly),
+		"to":     query.To.Format(time.DateOnly),
+		"tz":     query.Location.String(),
+		"list":   query.List,
+		"points": points,
+	}, points)
+}
+
+func (h *Handler) handleFlow(w http.ResponseWriter, r *http.Request) {
+	// 累积流图：?from=&to=&list=&tz=，每天结束时各状态的数量
+	query, err := parseFlowQuery(r, time.Now())
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	days, err := h.store.DailyFlow(r.Context(), query)
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to load flow")
+		return
+	}
+
+	h.respond(w, r, map[string]any{
+		"from": query.From.Format(time.DateOnly),
+		"to":   query.To.Format(time.DateOnly),
+		"tz":   query.Location.String(),
+		"list": query.List,
+		"days": days,
+	}, days)
+}
+
+func (h *Handler) handleMyStats(w http.ResponseWriter, r *http.Request) {
+	// 当前用户的个人统计，?tz= 决定连续完成天数按哪个时区划分日期
+	identity, _ := auth.FromContext(r.Context())
+	location, err := parseLocation(r.URL.Query().Get("tz"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	summary, err := h.store.UserSummary(r.Context(), identity.UserID, location)
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to load stats")
+		return
+	}
+	h.respond(w, r, summary, summary)
+}
+
+func (h *Handler) handleMyListStats(w http.ResponseWriter, r *http.Request) {
+	// 当前用户按清单拆分的统计
+	identity, _ := auth.FromContext(r.Context())
+	lists, err := h.store.UserListSummaries(r.Context(), identity.UserID)
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to load stats")
+		return
+	}
+	h.respond(w, r, map[string]any{"lists": lists}, lists)
+}
+
+func (h *Handler) handleLeaderboard(w http.ResponseWriter, r *http.Request) {
+	// 排行榜：?window=&by=&limit=&offset=
+	query, err := parseLeaderboardQuery(r, time.Now())
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, err := h.store.Leaderboard(r.Context(), query, h.weights)
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to load leaderboard")
+		return
+	}
+	h.respond(w, r, map[string]any{
+		"window":  query.Window,
+		"by":      query.By,
+		"total":   page.Total,
+		"limit":   query.Limit,
+		"offset":  query.Offset,
+		"entries": page.Entries,
+	}, page.Entries)
+}
+
+func (h *Handler) handleMyRank(w http.ResponseWriter, r *http.Request) {
+	// 当前用户在排行榜中的名次，窗口内没有完成记录时 entry 为 null
+	identity, _ := auth.FromContext(r.Context())
+	query, err := parseLeaderboardQuery(r, time.Now())
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	entry, err := h.store.LeaderboardRank(r.Context(), query, h.weights, identity.UserID)
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to load leaderboard")
+		return
+	}
+	// CSV 中没有名次时输出只有表头的空表
+	entries := []LeaderboardEntry{}
+	if entry != nil {
+		entries = append(entries, *entry)
+	}
+	h.respond(w, r, map[string]any{
+		"window": query.Window,
+		"by":     query.By,
+		"entry":  entry,
+	}, entries)
+}
+
+func (h *Handler) handleStatsByUser(w http.ResponseWriter, r *http.Request) {
+	// 管理员：按用户拆分的统计，支持 ?limit=&offset= 分页
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	users, err := h.store.UserBreakdown(r.Context(), limit, offset)
 	if err != nil {
 		h.writeError(w, http.StatusInternalServerError, "failed to load stats")
 		return
 	}
-	h.writeJSON(w, http.StatusOK, summary)
+	h.respond(w, r, map[string]any{
+		"users":  users,
+		"limit":  limit,
+		"offset": offset,
+	}, users)
+}
+
+func (h *Handler) handleUserStats(w http.ResponseWriter, r *http.Request) {
+	// 管理员：用户侧统计，时间序列参数与 /stats/timeseries 相同；CSV 只导出时间序列
+	query, err := parseTimeseriesQuery(r, time.Now())
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	buckets, err := h.store.UserActivity(ctx, query)
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to load user stats")
+		return
+	}
+	active, err := h.store.ActiveUsers(ctx)
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to load user stats")
+		return
+	}
+	distribution, err := h.store.TodoDistribution(ctx)
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to load user stats")
+		return
+	}
+
+	h.respond(w, r, map[string]any{
+		"from":           query.From.In(query.Location),
+		"to":             query.To.In(query.Location),
+		"interval":       query.Interval,
+		"tz":             query.Location.String(),
+		"buckets":        buckets,
+		"active_users":   active,
+		"todos_per_user": distribution,
+	}, buckets)
+}
+
+func (h *Handler) handleQuery(w http.ResponseWriter, r *http.Request) {
+	// 管理员：按 JSON DSL 执行临时统计查询，编译为参数化 SQL 后在只读事务中执行
+	var input AdHocQuery
+	if err := h.decodeJSON(w, r, &input); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	compiled, err := compileQuery(input)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := h.store.RunQuery(r.Context(), compiled, h.queryMaxCost, h.queryTimeout)
+	if err != nil {
+		if errors.Is(err, ErrQueryTooExpensive) {
+			h.writeError(w, http.StatusBadRequest, "query too expensive, add filters or fewer dimensions")
+			return
+		}
+		if isQueryCanceled(err) {
+			h.writeError(w, http.StatusServiceUnavailable, "query timed out")
+			return
+		}
+		h.writeError(w, http.StatusInternalServerError, "failed to run query")
+		return
+	}
+	h.respond(w, r, map[string]any{
+		"columns": result.Columns,
+		"rows":    result.Rows,
+	}, result)
+}
+
+func (h *Handler) respond(w http.ResponseWriter, r *http.Request, body any, rows any) {
+	// 按 Accept 协商响应格式：JSON 返回完整的 body，CSV 只输出 rows 这张表；
+	// 使用只读副本时 as_of 扣除了复制延迟
+	w.Header().Set("Vary", "Accept")
+	if fields, ok := body.(map[string]any); ok {
+		fields["as_of"] = h.store.AsOf(time.Now())
+	}
+	if !wantsCSV(r) {
+		h.writeJSON(w, http.StatusOK, body)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := encodeCSV(&buf, rows); err != nil {
+		h.logger.Printf("csv encode error: %v", err)
+		h.writeError(w, http.StatusInternalServerError, "failed to encode response")
+		return
+	}
+	w.Header().Set("Content-Type", csvContentType+"; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		h.logger.Printf("write response error: %v", err)
+	}
+}
+
+func (h *Handler) decodeJSON(w http.ResponseWriter, r *http.Request, dst any) error {
+	// 限制请求体大小并严格解析 JSON
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		return err
+	}
+	if err := dec.Decode(&struct{}{}); err != io.EOF {
+		return errors.New("body must contain a single JSON object")
+	}
+	return nil
 }
 
 func (h *Handler) writeJSON(w http.ResponseWriter, status int, v any) {
@@ -61,7 +414,50 @@ func (h *Handler) writeJSON(w http.ResponseWriter, status int, v any) {
 	}
 }
 
+func (h *Handler) writeCachedJSON(w http.ResponseWriter, r *http.Request, v, validator any, maxAge time.Duration) {
+	// 以 validator 的摘要作为 ETag，客户端携带相同的 If-None-Match 时返回 304；
+	// validator 只包含数据字段，as_of 这类每次刷新都会变化的字段不参与计算
+	tag, err := json.Marshal(validator)
+	if err != nil {
+		h.logger.Printf("json encode error: %v", err)
+		h.writeError(w, http.StatusInternalServerError, "failed to encode response")
+		return
+	}
+	sum := sha256.Sum256(tag)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds())))
+	if match := r.Header.Get("If-None-Match"); match != "" && etagMatches(match, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	body, err := json.Marshal(v)
+	if err != nil {
+		h.logger.Printf("json encode error: %v", err)
+		h.writeError(w, http.StatusInternalServerError, "failed to encode response")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(append(body, '\n')); err != nil {
+		h.logger.Printf("write response error: %v", err)
+	}
+}
+
 func (h *Handler) writeError(w http.ResponseWriter, status int, message string) {
 	// 错误响应包装
 	h.writeJSON(w, status, map[string]string{"error": message})
 }
+
+func etagMatches(header, etag string) bool {
+	// If-None-Match 可能包含多个以逗号分隔的 ETag，或弱校验前缀 W/
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
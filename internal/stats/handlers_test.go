@@ -0,0 +1,41 @@
+package stats
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWriteCachedJSONIgnoresAsOf(t *testing.T) {
+	h := &Handler{logger: log.New(io.Discard, "", 0)}
+	first := Summary{Total: 10, Done: 4, Archived: 1, AsOf: time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC)}
+
+	rec := httptest.NewRecorder()
+	h.writeCachedJSON(rec, httptest.NewRequest(http.MethodGet, "/stats", nil), first, first.counts(), 5*time.Second)
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || etag == "" {
+		t.Fatalf("first response: status %d etag %q", rec.Code, etag)
+	}
+
+	// 缓存刷新后 as_of 变化但计数不变，仍应返回 304
+	refreshed := first
+	refreshed.AsOf = first.AsOf.Add(5 * time.Second)
+	req := httptest.NewRequest(http.MethodGet, "/stats", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	h.writeCachedJSON(rec, req, refreshed, refreshed.counts(), 5*time.Second)
+	if rec.Code != http.StatusNotModified {
+		t.Fatalf("unchanged counts: status %d, want 304", rec.Code)
+	}
+
+	changed := refreshed
+	changed.Done++
+	rec = httptest.NewRecorder()
+	h.writeCachedJSON(rec, req, changed, changed.counts(), 5*time.Second)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Fatalf("changed counts: status %d etag %q", rec.Code, rec.Header().Get("ETag"))
+	}
+}
@@ -0,0 +1,167 @@
+package stats
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultPriorityWeights 是按优先级计分时每完成一个 todo 得到的积分
+var DefaultPriorityWeights = map[string]int64{
+	"low":    1,
+	"medium": 2,
+	"high":   3,
+	"urgent": 5,
+}
+
+// LeaderboardQuery 描述排行榜的统计窗口与排序依据，Since 为 nil 时统计全部历史
+type LeaderboardQuery struct {
+	Since  *time.Time
+	Window string
+	// By 为 completed（完成数）或 points（按优先级加权的积分）
+	By     string
+	Limit  int
+	Offset int
+}
+
+// LeaderboardEntry 是排行榜中的一行，分数相同的用户名次相同（dense rank）
+type LeaderboardEntry struct {
+	Rank      int64  `json:"rank"`
+	UserID    int64  `json:"user_id"`
+	Name      string `json:"name"`
+	Completed int64  `json:"completed"`
+	Points    int64  `json:"points"`
+}
+
+// LeaderboardPage 是排行榜的一页，Total 为参与排名的总人数
+type LeaderboardPage struct {
+	Entries []LeaderboardEntry
+	Total   int64
+}
+
+// leaderboardCTE 计算窗口内每个用户的完成数与积分并排名，参数：
+// $1 权重 JSON，$2 窗口起点（可为 NULL），$3 排序依据
+const leaderboardCTE = `
+	WITH weights AS (
+		SELECT key AS priority, value::bigint AS weight
+		FROM jsonb_each_text($1::jsonb)
+	),
+	scores AS (
+		SELECT t.user_id,
+			COUNT(*) AS completed,
+			COALESCE(SUM(w.weight), 0) AS points
+		FROM todos t
+		LEFT JOIN weights w ON w.priority = t.priority
+		WHERE t.status = 'done'
+			AND t.user_id IS NOT NULL
+			AND ($2::timestamptz IS NULL OR t.completed_at >= $2)
+		GROUP BY t.user_id
+	),
+	ranked AS (
+		SELECT s.user_id, s.completed, s.points,
+			DENSE_RANK() OVER (ORDER BY CASE WHEN $3 = 'points' THEN s.points ELSE s.completed END DESC) AS rank,
+			COUNT(*) OVER () AS ranked_users
+		FROM scores s
+	)
+`
+
+func (s *Store) Leaderboard(ctx context.Context, query LeaderboardQuery, weights map[string]int64) (LeaderboardPage, error) {
+	// 返回当前页的排名以及参与排名的总人数；同名次按 user_id 排序保证分页稳定
+	weightsJSON, err := json.Marshal(weights)
+	if err != nil {
+		return LeaderboardPage{}, err
+	}
+
+	return read(ctx, s, func(db *sql.DB) (LeaderboardPage, error) {
+		rows, err := db.QueryContext(ctx, leaderboardCTE+`
+			SELECT r.rank, r.user_id, u.name, r.completed, r.points, r.ranked_users
+			FROM ranked r
+			JOIN users u ON u.id = r.user_id
+			ORDER BY r.rank, r.user_id
+			LIMIT $4 OFFSET $5
+		`, string(weightsJSON), query.Since, query.By, query.Limit, query.Offset)
+		if err != nil {
+			return LeaderboardPage{}, err
+		}
+		defer rows.Close()
+
+		page := LeaderboardPage{Entries: []LeaderboardEntry{}}
+		for rows.Next() {
+			var entry LeaderboardEntry
+			if err := rows.Scan(&entry.Rank, &entry.UserID, &entry.Name, &entry.Completed, &entry.Points, &page.Total); err != nil {
+				return LeaderboardPage{}, err
+			}
+			page.Entries = append(page.Entries, entry)
+		}
+		if err := rows.Err(); err != nil {
+			return LeaderboardPage{}, err
+		}
+		if len(page.Entries) == 0 && query.Offset > 0 {
+			// 越过最后一页时仍需要总人数
+			row := db.QueryRowContext(ctx, leaderboardCTE+`SELECT COUNT(*) FROM ranked`, string(weightsJSON), query.Since, query.By)
+			if err := row.Scan(&page.Total); err != nil {
+				return LeaderboardPage{}, err
+			}
+		}
+		return page, nil
+	})
+}
+
+func (s *Store) LeaderboardRank(ctx context.Context, query LeaderboardQuery, weights map[string]int64, userID int64) (*LeaderboardEntry, error) {
+	// 查询某个用户的名次；窗口内没有完成任何 todo 的用户不参与排名，返回 nil
+	return read(ctx, s, func(db *sql.DB) (*LeaderboardEntry, error) {
+		weightsJSON, err := json.Marshal(weights)
+		if err != nil {
+			return nil, err
+		}
+
+		var entry LeaderboardEntry
+		var total int64
+		row := db.QueryRowContext(ctx, leaderboardCTE+`
+			SELECT r.rank, r.user_id, u.name, r.completed, r.points, r.ranked_users
+			FROM ranked r
+			JOIN users u ON u.id = r.user_id
+			WHERE r.user_id = $4
+		`, string(weightsJSON), query.Since, query.By, userID)
+		if err := row.Scan(&entry.Rank, &entry.UserID, &entry.Name, &entry.Completed, &entry.Points, &total); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		return &entry, nil
+	})
+}
+
+// ParsePriorityWeights 解析形如 "low=1,medium=2,high=3,urgent=5" 的权重配置，未列出的优先级沿用默认值
+func ParsePriorityWeights(value string) (map[string]int64, error) {
+	weights := make(map[string]int64, len(DefaultPriorityWeights))
+	for priority, weight := range DefaultPriorityWeights {
+		weights[priority] = weight
+	}
+	if strings.TrimSpace(value) == "" {
+		return weights, nil
+	}
+
+	for _, part := range strings.Split(value, ",") {
+		priority, raw, ok := strings.Cut(strings.TrimSpace(part), "=")
+		priority = strings.TrimSpace(priority)
+		if !ok {
+			return nil, fmt.Errorf("invalid weight %q, expected priority=weight", part)
+		}
+		if _, known := DefaultPriorityWeights[priority]; !known {
+			return nil, fmt.Errorf("unknown priority %q", priority)
+		}
+		weight, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid weight for %s", priority)
+		}
+		weights[priority] = weight
+	}
+	return weights, nil
+}
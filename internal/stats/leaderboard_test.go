@@ -0,0 +1,29 @@
+package stats
+
+import "testing"
+
+func TestParsePriorityWeights(t *testing.T) {
+	weights, err := ParsePriorityWeights("high=10, urgent=20")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	want := map[string]int64{"low": 1, "medium": 2, "high": 10, "urgent": 20}
+	for priority, weight := range want {
+		if weights[priority] != weight {
+			t.Fatalf("weight for %s: got %d want %d", priority, weights[priority], weight)
+		}
+	}
+
+	// 默认权重不能被解析结果修改
+	if DefaultPriorityWeights["high"] != 3 {
+		t.Fatalf("default weights modified: %#v", DefaultPriorityWeights)
+	}
+}
+
+func TestParsePriorityWeightsInvalid(t *testing.T) {
+	for _, value := range []string{"high", "critical=3", "low=-1", "medium=x"} {
+		if _, err := ParsePriorityWeights(value); err == nil {
+			t.Fatalf("expected error for %q", value)
+		}
+	}
+}
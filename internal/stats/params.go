@@ -0,0 +1,176 @@
+package stats
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	maxBuckets      = 1000
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+// bucketSpan 是每种分桶粒度的近似长度，只用于限制区间数量
+var bucketSpan = map[string]time.Duration{
+	"day":   24 * time.Hour,
+	"week":  7 * 24 * time.Hour,
+	"month": 28 * 24 * time.Hour,
+}
+
+func parseTimeseriesQuery(r *http.Request, now time.Time) (TimeseriesQuery, error) {
+	// 解析并校验时间序列参数，默认统计最近 30 天、按天、UTC
+	values := r.URL.Query()
+
+	query := TimeseriesQuery{Interval: "day", Location: time.UTC}
+	if value := values.Get("interval"); value != "" {
+		query.Interval = value
+	}
+	span, ok := bucketSpan[query.Interval]
+	if !ok {
+		return TimeseriesQuery{}, errors.New("interval must be day, week or month")
+	}
+
+	location, err := parseLocation(values.Get("tz"))
+	if err != nil {
+		return TimeseriesQuery{}, err
+	}
+	query.Location = location
+
+	query.To = now
+	if value := values.Get("to"); value != "" {
+		if query.To, err = parseTimeParam(value, query.Location); err != nil {
+			return TimeseriesQuery{}, errors.New("invalid to")
+		}
+	}
+	query.From = query.To.AddDate(0, 0, -30)
+	if value := values.Get("from"); value != "" {
+		if query.From, err = parseTimeParam(value, query.Location); err != nil {
+			return TimeseriesQuery{}, errors.New("invalid from")
+		}
+	}
+
+	if !query.From.Before(query.To) {
+		return TimeseriesQuery{}, errors.New("from must be before to")
+	}
+	if query.To.Sub(query.From)/span > maxBuckets {
+		return TimeseriesQuery{}, fmt.Errorf("range too large, at most %d buckets", maxBuckets)
+	}
+	return query, nil
+}
+
+func parseFlowQuery(r *http.Request, now time.Time) (FlowQuery, error) {
+	// 解析燃尽图/累积流图的参数：?from=&to= 为 YYYY-MM-DD（含两端），默认最近 30 天，
+	// ?list= 只统计某个清单
+	values := r.URL.Query()
+
+	location, err := parseLocation(values.Get("tz"))
+	if err != nil {
+		return FlowQuery{}, err
+	}
+	query := FlowQuery{Location: location}
+
+	today := now.In(location)
+	query.To = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, location)
+	if value := values.Get("to"); value != "" {
+		if query.To, err = time.ParseInLocation(time.DateOnly, value, location); err != nil {
+			return FlowQuery{}, errors.New("invalid to")
+		}
+	}
+	query.From = query.To.AddDate(0, 0, -30)
+	if value := values.Get("from"); value != "" {
+		if query.From, err = time.ParseInLocation(time.DateOnly, value, location); err != nil {
+			return FlowQuery{}, errors.New("invalid from")
+		}
+	}
+
+	if query.To.Before(query.From) {
+		return FlowQuery{}, errors.New("from must not be after to")
+	}
+	if query.To.Sub(query.From)/bucketSpan["day"] >= maxBuckets {
+		return FlowQuery{}, fmt.Errorf("range too large, at most %d days", maxBuckets)
+	}
+
+	if value := strings.TrimSpace(values.Get("list")); value != "" {
+		query.List = &value
+	}
+	return query, nil
+}
+
+func parseLeaderboardQuery(r *http.Request, now time.Time) (LeaderboardQuery, error) {
+	// 解析 ?window=7d|30d|all&by=completed|points 以及分页参数，默认最近 30 天按完成数排名
+	values := r.URL.Query()
+	query := LeaderboardQuery{Window: "30d", By: "completed"}
+
+	if value := values.Get("window"); value != "" {
+		query.Window = value
+	}
+	if query.Window != "all" {
+		days, err := strconv.Atoi(strings.TrimSuffix(query.Window, "d"))
+		if err != nil || !strings.HasSuffix(query.Window, "d") || days < 1 || days > maxBuckets {
+			return LeaderboardQuery{}, fmt.Errorf("window must be all or between 1d and %dd", maxBuckets)
+		}
+		since := now.AddDate(0, 0, -days)
+		query.Since = &since
+	}
+
+	if value := values.Get("by"); value != "" {
+		query.By = value
+	}
+	if query.By != "completed" && query.By != "points" {
+		return LeaderboardQuery{}, errors.New("by must be completed or points")
+	}
+
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		return LeaderboardQuery{}, err
+	}
+	query.Limit, query.Offset = limit, offset
+	return query, nil
+}
+
+func parseTimeParam(value string, location *time.Location) (time.Time, error) {
+	// 支持 RFC3339 时间或 YYYY-MM-DD 日期（按所选时区的零点解释）
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, nil
+	}
+	return time.ParseInLocation(time.DateOnly, value, location)
+}
+
+func parseLocation(value string) (*time.Location, error) {
+	// 未指定时区时使用 UTC
+	if value == "" {
+		return time.UTC, nil
+	}
+	location, err := time.LoadLocation(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tz %q", value)
+	}
+	return location, nil
+}
+
+func parsePagination(r *http.Request) (int, int, error) {
+	// 解析 ?limit=&offset=，limit 默认 50、最大 200
+	values := r.URL.Query()
+	limit := defaultPageSize
+	if value := values.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxPageSize {
+			return 0, 0, fmt.Errorf("limit must be between 1 and %d", maxPageSize)
+		}
+		limit = parsed
+	}
+	offset := 0
+	if value := values.Get("offset"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			return 0, 0, errors.New("invalid offset")
+		}
+		offset = parsed
+	}
+	return limit, offset, nil
+}
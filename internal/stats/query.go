@@ -0,0 +1,343 @@
+package stats
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	maxQueryGroupBy    = 3
+	maxQueryAggregates = 5
+	maxQueryFilters    = 10
+	defaultQueryLimit  = 100
+	maxQueryLimit      = 1000
+)
+
+// ErrQueryTooExpensive 表示查询计划的估算代价超过上限，查询不会被执行
+var ErrQueryTooExpensive = errors.New("query too expensive")
+
+// AdHocQuery 是 POST /stats/query 接受的 DSL：按白名单维度分组，对 todo 做 count/avg 聚合
+type AdHocQuery struct {
+	GroupBy    []string         `json:"group_by"`
+	Aggregates []QueryAggregate `json:"aggregates"`
+	Filters    []QueryFilter    `json:"filters"`
+	TZ         string           `json:"tz"`
+	Limit      int              `json:"limit"`
+}
+
+// QueryAggregate 是一个聚合列，count 不需要 field，avg 需要数值字段
+type QueryAggregate struct {
+	Op    string `json:"op"`
+	Field string `json:"field"`
+}
+
+// QueryFilter 是一个过滤条件，Value 的类型由 Field 决定
+type QueryFilter struct {
+	Field string          `json:"field"`
+	Op    string          `json:"op"`
+	Value json.RawMessage `json:"value"`
+}
+
+// QueryResult 是查询结果，每行按 Columns 的顺序给出值
+type QueryResult struct {
+	Columns []string `json:"columns"`
+	Rows    [][]any  `json:"rows"`
+}
+
+type fieldKind int
+
+const (
+	kindText fieldKind = iota
+	kindBool
+	kindTime
+)
+
+// queryDimensions 是允许分组的维度及其 SQL 表达式；时间维度按 created_at 在所选时区内截断，
+// {tz} 在编译时替换为时区参数的占位符
+var queryDimensions = map[string]string{
+	"done":     "done",
+	"status":   "status",
+	"priority": "priority",
+	"list":     "COALESCE(list_name, '')",
+	"archived": "archived_at IS NOT NULL",
+	"day":      "date_trunc('day', created_at AT TIME ZONE {tz})",
+	"week":     "date_trunc('week', created_at AT TIME ZONE {tz})",
+	"month":    "date_trunc('month', created_at AT TIME ZONE {tz})",
+}
+
+// queryMeasures 是 avg 可以使用的数值字段
+var queryMeasures = map[string]string{
+	"hours_to_complete": "EXTRACT(EPOCH FROM completed_at - created_at) / 3600",
+	"hours_overdue":     "EXTRACT(EPOCH FROM COALESCE(completed_at, NOW()) - due_at) / 3600",
+}
+
+// queryFilterFields 是允许过滤的字段及其类型
+var queryFilterFields = map[string]struct {
+	column string
+	kind   fieldKind
+}{
+	"status":       {"status", kindText},
+	"priority":     {"priority", kindText},
+	"list":         {"list_name", kindText},
+	"done":         {"done", kindBool},
+	"archived":     {"archived_at IS NOT NULL", kindBool},
+	"created_at":   {"created_at", kindTime},
+	"completed_at": {"completed_at", kindTime},
+	"due_at":       {"due_at", kindTime},
+}
+
+var filterOperators = map[fieldKind]map[string]string{
+	kindText: {"eq": "=", "ne": "<>", "in": "= ANY"},
+	kindBool: {"eq": "="},
+	kindTime: {"gt": ">", "gte": ">=", "lt": "<", "lte": "<="},
+}
+
+// compiledQuery 是编译后的参数化 SQL；SQL 中只出现白名单里的常量片段，用户输入全部通过 Args 传递
+type compiledQuery struct {
+	SQL     string
+	Args    []any
+	Columns []string
+}
+
+type queryCompiler struct {
+	args []any
+	tz   string
+}
+
+func (c *queryCompiler) arg(value any) string {
+	c.args = append(c.args, value)
+	return "$" + strconv.Itoa(len(c.args))
+}
+
+func compileQuery(query AdHocQuery) (compiledQuery, error) {
+	// 校验并编译 DSL，任何不在白名单中的维度、字段或操作符都会被拒绝
+	if len(query.GroupBy) > maxQueryGroupBy {
+		return compiledQuery{}, fmt.Errorf("at most %d group_by dimensions", maxQueryGroupBy)
+	}
+	if len(query.Aggregates) == 0 {
+		query.Aggregates = []QueryAggregate{{Op: "count"}}
+	}
+	if len(query.Aggregates) > maxQueryAggregates {
+		return compiledQuery{}, fmt.Errorf("at most %d aggregates", maxQueryAggregates)
+	}
+	if len(query.Filters) > maxQueryFilters {
+		return compiledQuery{}, fmt.Errorf("at most %d filters", maxQueryFilters)
+	}
+	limit := query.Limit
+	if limit == 0 {
+		limit = defaultQueryLimit
+	}
+	if limit < 1 || limit > maxQueryLimit {
+		return compiledQuery{}, fmt.Errorf("limit must be between 1 and %d", maxQueryLimit)
+	}
+	location, err := parseLocation(query.TZ)
+	if err != nil {
+		return compiledQuery{}, err
+	}
+
+	c := &queryCompiler{}
+	var columns, selects, groups []string
+	seen := map[string]bool{}
+	for _, name := range query.GroupBy {
+		expr, ok := queryDimensions[name]
+		if !ok {
+			return compiledQuery{}, fmt.Errorf("unknown group_by dimension %q", name)
+		}
+		if seen[name] {
+			return compiledQuery{}, fmt.Errorf("duplicate group_by dimension %q", name)
+		}
+		seen[name] = true
+		if strings.Contains(expr, "{tz}") {
+			if c.tz == "" {
+				c.tz = c.arg(location.String())
+			}
+			expr = strings.ReplaceAll(expr, "{tz}", c.tz)
+		}
+		columns = append(columns, name)
+		selects = append(selects, expr)
+		groups = append(groups, strconv.Itoa(len(selects)))
+	}
+
+	for _, aggregate := range query.Aggregates {
+		switch aggregate.Op {
+		case "count":
+			if aggregate.Field != "" {
+				return compiledQuery{}, errors.New("count does not take a field")
+			}
+			columns = append(columns, "count")
+			selects = append(selects, "COUNT(*)")
+		case "avg":
+			expr, ok := queryMeasures[aggregate.Field]
+			if !ok {
+				return compiledQuery{}, fmt.Errorf("unknown avg field %q", aggregate.Field)
+			}
+			columns = append(columns, "avg_"+aggregate.Field)
+			selects = append(selects, "ROUND(AVG("+expr+")::numeric, 2)::float8")
+		default:
+			return compiledQuery{}, fmt.Errorf("unknown aggregate %q, must be count or avg", aggregate.Op)
+		}
+	}
+
+	var conditions []string
+	for _, filter := range query.Filters {
+		condition, err := c.filter(filter)
+		if err != nil {
+			return compiledQuery{}, err
+		}
+		conditions = append(conditions, condition)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("SELECT " + strings.Join(selects, ", ") + " FROM todos")
+	if len(conditions) > 0 {
+		sb.WriteString(" WHERE " + strings.Join(conditions, " AND "))
+	}
+	if len(groups) > 0 {
+		sb.WriteString(" GROUP BY " + strings.Join(groups, ", "))
+		sb.WriteString(" ORDER BY " + strings.Join(groups, ", "))
+	}
+	sb.WriteString(" LIMIT " + c.arg(limit))
+
+	return compiledQuery{SQL: sb.String(), Args: c.args, Columns: columns}, nil
+}
+
+func (c *queryCompiler) filter(filter QueryFilter) (string, error) {
+	field, ok := queryFilterFields[filter.Field]
+	if !ok {
+		return "", fmt.Errorf("unknown filter field %q", filter.Field)
+	}
+	operator, ok := filterOperators[field.kind][filter.Op]
+	if !ok {
+		return "", fmt.Errorf("operator %q not supported for %s", filter.Op, filter.Field)
+	}
+
+	invalid := fmt.Errorf("invalid value for %s", filter.Field)
+	switch field.kind {
+	case kindText:
+		if filter.Op == "in" {
+			var values []string
+			if err := json.Unmarshal(filter.Value, &values); err != nil || len(values) == 0 {
+				return "", invalid
+			}
+			return field.column + " " + operator + "(" + c.arg(values) + "::text[])", nil
+		}
+		var value string
+		if err := json.Unmarshal(filter.Value, &value); err != nil {
+			return "", invalid
+		}
+		return field.column + " " + operator + " " + c.arg(value), nil
+	case kindBool:
+		var value bool
+		if err := json.Unmarshal(filter.Value, &value); err != nil {
+			return "", invalid
+		}
+		return "(" + field.column + ") " + operator + " " + c.arg(value), nil
+	default:
+		var value time.Time
+		if err := json.Unmarshal(filter.Value, &value); err != nil {
+			return "", invalid
+		}
+		return field.column + " " + operator + " " + c.arg(value), nil
+	}
+}
+
+func (s *Store) RunQuery(ctx context.Context, query compiledQuery, maxCost float64, timeout time.Duration) (QueryResult, error) {
+	// 在只读事务中执行：先用 EXPLAIN 估算代价，超过上限直接拒绝；statement_timeout 兜底限制执行时间
+	return read(ctx, s, func(db *sql.DB) (QueryResult, error) {
+		tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+		if err != nil {
+			return QueryResult{}, err
+		}
+		defer tx.Rollback()
+
+		if _, err := tx.ExecContext(ctx, `SELECT set_config('statement_timeout', $1, true)`,
+			strconv.FormatInt(timeout.Milliseconds(), 10)); err != nil {
+			return QueryResult{}, err
+		}
+
+		var plan string
+		if err := tx.QueryRowContext(ctx, "EXPLAIN (FORMAT JSON) "+query.SQL, query.Args...).Scan(&plan); err != nil {
+			return QueryResult{}, err
+		}
+		cost, err := planCost(plan)
+		if err != nil {
+			return QueryResult{}, err
+		}
+		if cost > maxCost {
+			return QueryResult{}, ErrQueryTooExpensive
+		}
+
+		rows, err := tx.QueryContext(ctx, query.SQL, query.Args...)
+		if err != nil {
+			return QueryResult{}, err
+		}
+		defer rows.Close()
+
+		result := QueryResult{Columns: query.Columns, Rows: [][]any{}}
+		for rows.Next() {
+			values := make([]any, len(query.Columns))
+			pointers := make([]any, len(values))
+			for i := range values {
+				pointers[i] = &values[i]
+			}
+			if err := rows.Scan(pointers...); err != nil {
+				return QueryResult{}, err
+			}
+			for i, value := range values {
+				// 时间维度是不带时区的截断结果，只输出日期
+				if t, ok := value.(time.Time); ok {
+					values[i] = t.Format(time.DateOnly)
+				}
+			}
+			result.Rows = append(result.Rows, values)
+		}
+		if err := rows.Err(); err != nil {
+			return QueryResult{}, err
+		}
+		return result, nil
+	})
+}
+
+func isQueryCanceled(err error) bool {
+	// 57014 是 statement_timeout 触发的 query_canceled
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "57014"
+}
+
+func planCost(plan string) (float64, error) {
+	// EXPLAIN (FORMAT JSON) 的结果是只有一个元素的数组
+	var parsed []struct {
+		Plan struct {
+			TotalCost float64 `json:"Total Cost"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal([]byte(plan), &parsed); err != nil {
+		return 0, fmt.Errorf("unexpected explain output: %w", err)
+	}
+	if len(parsed) == 0 {
+		return 0, errors.New("unexpected explain output: empty plan")
+	}
+	return parsed[0].Plan.TotalCost, nil
+}
+
+func (r QueryResult) csvRecords() ([]string, [][]string) {
+	records := make([][]string, 0, len(r.Rows))
+	for _, row := range r.Rows {
+		record := make([]string, len(row))
+		for i, value := range row {
+			if value != nil {
+				record[i] = fmt.Sprint(value)
+			}
+		}
+		records = append(records, record)
+	}
+	return r.Columns, records
+}
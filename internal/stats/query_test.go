@@ -0,0 +1,82 @@
+package stats
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompileQuery(t *testing.T) {
+	var query AdHocQuery
+	if err := json.Unmarshal([]byte(`{
+		"group_by": ["priority", "week"],
+		"aggregates": [{"op": "count"}, {"op": "avg", "field": "hours_to_complete"}],
+		"filters": [
+			{"field": "status", "op": "in", "value": ["done", "cancelled"]},
+			{"field": "created_at", "op": "gte", "value": "2024-01-01T00:00:00Z"}
+		],
+		"tz": "Asia/Shanghai",
+		"limit": 20
+	}`), &query); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	compiled, err := compileQuery(query)
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+
+	wantSQL := "SELECT priority, date_trunc('week', created_at AT TIME ZONE $1), COUNT(*), " +
+		"ROUND(AVG(EXTRACT(EPOCH FROM completed_at - created_at) / 3600)::numeric, 2)::float8 " +
+		"FROM todos WHERE status = ANY($2::text[]) AND created_at >= $3 " +
+		"GROUP BY 1, 2 ORDER BY 1, 2 LIMIT $4"
+	if compiled.SQL != wantSQL {
+		t.Fatalf("unexpected sql:\n%s", compiled.SQL)
+	}
+	wantArgs := []any{"Asia/Shanghai", []string{"done", "cancelled"}, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 20}
+	if !reflect.DeepEqual(compiled.Args, wantArgs) {
+		t.Fatalf("unexpected args: %#v", compiled.Args)
+	}
+	wantColumns := []string{"priority", "week", "count", "avg_hours_to_complete"}
+	if !reflect.DeepEqual(compiled.Columns, wantColumns) {
+		t.Fatalf("unexpected columns: %#v", compiled.Columns)
+	}
+}
+
+func TestCompileQueryKeepsUserInputOutOfSQL(t *testing.T) {
+	injection := "x'; DROP TABLE todos; --"
+	compiled, err := compileQuery(AdHocQuery{
+		Filters: []QueryFilter{{Field: "list", Op: "eq", Value: json.RawMessage(`"` + injection + `"`)}},
+	})
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	if strings.Contains(compiled.SQL, "DROP") {
+		t.Fatalf("user input leaked into sql: %s", compiled.SQL)
+	}
+	if compiled.SQL != "SELECT COUNT(*) FROM todos WHERE list_name = $1 LIMIT $2" {
+		t.Fatalf("unexpected sql: %s", compiled.SQL)
+	}
+}
+
+func TestCompileQueryRejectsUnknownInput(t *testing.T) {
+	cases := []AdHocQuery{
+		{GroupBy: []string{"title"}},
+		{GroupBy: []string{"done", "done"}},
+		{GroupBy: []string{"done", "status", "priority", "list"}},
+		{Aggregates: []QueryAggregate{{Op: "sum", Field: "hours_to_complete"}}},
+		{Aggregates: []QueryAggregate{{Op: "avg", Field: "id"}}},
+		{Filters: []QueryFilter{{Field: "title", Op: "eq", Value: json.RawMessage(`"a"`)}}},
+		{Filters: []QueryFilter{{Field: "done", Op: "in", Value: json.RawMessage(`[true]`)}}},
+		{Filters: []QueryFilter{{Field: "created_at", Op: "gte", Value: json.RawMessage(`"yesterday"`)}}},
+		{TZ: "Mars/Olympus"},
+		{Limit: maxQueryLimit + 1},
+	}
+	for i, query := range cases {
+		if _, err := compileQuery(query); err == nil {
+			t.Fatalf("case %d: expected error", i)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package stats
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"go_test/internal/database"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// replicaState 记录只读副本的健康状况与复制延迟，由 ReplicaMonitor 定期刷新
+type replicaState struct {
+	db *sql.DB
+
+	mu      sync.RWMutex
+	healthy bool
+	lag     time.Duration
+}
+
+func (r *replicaState) current() (*sql.DB, time.Duration, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.db, r.lag, r.healthy
+}
+
+func (r *replicaState) set(healthy bool, lag time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.healthy = healthy
+	r.lag = lag
+}
+
+// WithReplica 让只读统计查询优先走副本，副本出错时回退到主库
+func (s *Store) WithReplica(replica *sql.DB) *Store {
+	s.replica = &replicaState{db: replica, healthy: true}
+	return s
+}
+
+// AsOf 返回统计数据对应的时间点：使用副本时扣除最近一次测得的复制延迟
+func (s *Store) AsOf(now time.Time) time.Time {
+	if s.replica == nil {
+		return now
+	}
+	if _, lag, healthy := s.replica.current(); healthy {
+		return now.Add(-lag)
+	}
+	return now
+}
+
+func read[T any](ctx context.Context, s *Store, fn func(db *sql.DB) (T, error)) (T, error) {
+	// 优先在健康的副本上执行，副本故障时把它标记为不健康并在主库上重试
+	if s.replica != nil {
+		if replica, lag, healthy := s.replica.current(); healthy {
+			result, err := fn(replica)
+			if err == nil || ctx.Err() != nil || !isReplicaFailure(err) {
+				return result, err
+			}
+			s.replica.set(false, lag)
+		}
+	}
+	return fn(s.db)
+}
+
+func isReplicaFailure(err error) bool {
+	// 查不到数据、代价超限以及普通的 SQL 错误在主库上同样会发生，不算副本故障；
+	// 连接异常（08 类）与管理员中断（57P 类，如副本重启）才回退到主库
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, ErrQueryTooExpensive) {
+		return false
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return strings.HasPrefix(pgErr.Code, "08") || strings.HasPrefix(pgErr.Code, "57P")
+	}
+	return true
+}
+
+// ReplicaMonitor 定期检查副本的复制延迟，延迟过大或无法连接时统计查询改走主库
+type ReplicaMonitor struct {
+	store    *Store
+	logger   *log.Logger
+	interval time.Duration
+	maxLag   time.Duration
+}
+
+func NewReplicaMonitor(store *Store, logger *log.Logger, interval, maxLag time.Duration) *ReplicaMonitor {
+	return &ReplicaMonitor{
+		store:    store,
+		logger:   logger,
+		interval: interval,
+		maxLag:   maxLag,
+	}
+}
+
+func (m *ReplicaMonitor) Run(ctx context.Context) {
+	// 启动时先检查一次，之后按固定间隔检查，直到 ctx 被取消；未配置副本时直接返回
+	if m.store.replica == nil {
+		return
+	}
+	m.check(ctx)
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.check(ctx)
+		}
+	}
+}
+
+func (m *ReplicaMonitor) check(ctx context.Context) {
+	state := m.store.replica
+	_, _, wasHealthy := state.current()
+
+	checkCtx, cancel := context.WithTimeout(ctx, m.interval)
+	defer cancel()
+	lag, err := database.ReplicaLag(checkCtx, state.db)
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		state.set(false, 0)
+		if wasHealthy {
+			m.logger.Printf("replica check failed, routing stats to primary: %v", err)
+		}
+		return
+	}
+
+	healthy := lag <= m.maxLag
+	state.set(healthy, lag)
+	switch {
+	case wasHealthy && !healthy:
+		m.logger.Printf("replica lag %s exceeds %s, routing stats to primary", lag.Round(time.Millisecond), m.maxLag)
+	case !wasHealthy && healthy:
+		m.logger.Printf("replica healthy again, lag %s", lag.Round(time.Millisecond))
+	}
+}
@@ -0,0 +1,308 @@
+package stats
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"go_test/internal/notify"
+)
+
+// Report 是定时报表的内容：周期内每天的新建/完成数，以及生成时的总量
+type Report struct {
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+	Summary     Summary   `json:"summary"`
+	Created     int64     `json:"created"`
+	Completed   int64     `json:"completed"`
+	Days        []Bucket  `json:"days"`
+}
+
+// RenderedReport 是按某种格式渲染好的报表，交给 ReportSink 投递
+type RenderedReport struct {
+	Filename    string
+	Subject     string
+	ContentType string
+	Body        []byte
+}
+
+// ReportSink 抽象报表的投递方式（写文件、webhook、邮件）
+type ReportSink interface {
+	Deliver(ctx context.Context, report RenderedReport) error
+}
+
+func (s *Store) BuildReport(ctx context.Context, start, end time.Time, location *time.Location) (Report, error) {
+	// 复用按天分桶的时间序列，再附上当前汇总
+	days, err := s.Timeseries(ctx, TimeseriesQuery{From: start, To: end, Interval: "day", Location: location})
+	if err != nil {
+		return Report{}, err
+	}
+	summary, err := s.Summary(ctx)
+	if err != nil {
+		return Report{}, err
+	}
+
+	report := Report{
+		PeriodStart: start.In(location),
+		PeriodEnd:   end.In(location),
+		Summary:     summary,
+		Days:        days,
+	}
+	for _, day := range days {
+		report.Created += day.Created
+		report.Completed += day.Completed
+	}
+	return report, nil
+}
+
+func renderReport(report Report, format string) (RenderedReport, error) {
+	// 支持 json/csv/text 三种格式，文件名与标题按周期起止日期生成
+	from := report.PeriodStart.Format(time.DateOnly)
+	to := report.PeriodEnd.Format(time.DateOnly)
+	rendered := RenderedReport{
+		Filename: fmt.Sprintf("stats-report-%s-%s", from, to),
+		Subject:  fmt.Sprintf("Todo stats report %s - %s", from, to),
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(&buf)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return RenderedReport{}, err
+		}
+		rendered.Filename += ".json"
+		rendered.ContentType = "application/json"
+	case "csv":
+		writer := csv.NewWriter(&buf)
+		writer.Write([]string{"date", "created", "completed"})
+		for _, day := range report.Days {
+			writer.Write([]string{
+				day.Start.Format(time.DateOnly),
+				strconv.FormatInt(day.Created, 10),
+				strconv.FormatInt(day.Completed, 10),
+			})
+		}
+		writer.Write([]string{"total", strconv.FormatInt(report.Created, 10), strconv.FormatInt(report.Completed, 10)})
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return RenderedReport{}, err
+		}
+		rendered.Filename += ".csv"
+		rendered.ContentType = csvContentType
+	case "text":
+		fmt.Fprintf(&buf, "%s\n\n", rendered.Subject)
+		fmt.Fprintf(&buf, "Created:   %d\n", report.Created)
+		fmt.Fprintf(&buf, "Completed: %d\n\n", report.Completed)
+		for _, day := range report.Days {
+			fmt.Fprintf(&buf, "%s  created %d  completed %d\n", day.Start.Format(time.DateOnly), day.Created, day.Completed)
+		}
+		fmt.Fprintf(&buf, "\nAll todos: %d total, %d done, %d archived\n", report.Summary.Total, report.Summary.Done, report.Summary.Archived)
+		rendered.Filename += ".txt"
+		rendered.ContentType = "text/plain"
+	default:
+		return RenderedReport{}, fmt.Errorf("unknown report format %q", format)
+	}
+	rendered.Body = buf.Bytes()
+	return rendered, nil
+}
+
+// FileSink 把报表写入本地目录
+type FileSink struct {
+	dir string
+}
+
+func NewFileSink(dir string) *FileSink {
+	return &FileSink{dir: dir}
+}
+
+func (s *FileSink) Deliver(ctx context.Context, report RenderedReport) error {
+	// 先写临时文件再重命名，读取方不会看到写了一半的报表
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+	path := filepath.Join(s.dir, report.Filename)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, report.Body, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// NotifierSink 通过 notify 包的渠道投递报表，webhook 与 SMTP 复用同一套实现
+type NotifierSink struct {
+	notifier  notify.Notifier
+	recipient string
+}
+
+func NewNotifierSink(notifier notify.Notifier, recipient string) *NotifierSink {
+	return &NotifierSink{notifier: notifier, recipient: recipient}
+}
+
+func (s *NotifierSink) Deliver(ctx context.Context, report RenderedReport) error {
+	return s.notifier.Notify(ctx, notify.Notification{
+		Recipient: s.recipient,
+		Subject:   report.Subject,
+		Body:      string(report.Body),
+		Data: map[string]any{
+			"filename":     report.Filename,
+			"content_type": report.ContentType,
+		},
+	})
+}
+
+const (
+	// reportClaimLease 是认领一个周期后的投递时限，超时未完成的周期可被其他副本重新认领
+	reportClaimLease = 10 * time.Minute
+	// reportRetryDelay 是投递失败后的重试间隔
+	reportRetryDelay = 5 * time.Minute
+)
+
+// reportEpoch 是周期对齐的起点：2024-01-01 是周一，按周的报表因此对齐到自然周（周一 0 点）
+var reportEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// reportPeriod 返回 now 之前最近一个已结束的周期 [start, end)。
+// 周期从 reportEpoch 起按 interval 对齐；interval 为整天时按 location 的日历日计算，不受夏令时影响
+func reportPeriod(now time.Time, interval time.Duration, location *time.Location) (time.Time, time.Time) {
+	const day = 24 * time.Hour
+	if interval%day == 0 {
+		days := int(interval / day)
+		local := now.In(location)
+		today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+		elapsed := int(today.Sub(reportEpoch) / day)
+		current := elapsed - elapsed%days
+		if elapsed < 0 && elapsed%days != 0 {
+			current -= days
+		}
+		end := time.Date(reportEpoch.Year(), reportEpoch.Month(), reportEpoch.Day()+current, 0, 0, 0, 0, location)
+		start := time.Date(end.Year(), end.Month(), end.Day()-days, 0, 0, 0, 0, location)
+		return start, end
+	}
+
+	epoch := time.Date(reportEpoch.Year(), reportEpoch.Month(), reportEpoch.Day(), 0, 0, 0, 0, location)
+	periods := now.Sub(epoch) / interval
+	if now.Before(epoch) && now.Sub(epoch)%interval != 0 {
+		periods--
+	}
+	end := epoch.Add(periods * interval)
+	return end.Add(-interval), end
+}
+
+func (s *Store) ClaimReport(ctx context.Context, start, end time.Time, lease time.Duration) (bool, error) {
+	// 认领一个周期：未发送且没有被其他副本持有（或租约已过期）时才成功
+	var claimed time.Time
+	row := s.db.QueryRowContext(ctx, `
+		INSERT INTO stats_reports (period_start, period_end, claimed_until)
+		VALUES ($1, $2, NOW() + $3 * INTERVAL '1 second')
+		ON CONFLICT (period_start, period_end) DO UPDATE
+		SET claimed_until = EXCLUDED.claimed_until
+		WHERE stats_reports.sent_at IS NULL
+			AND (stats_reports.claimed_until IS NULL OR stats_reports.claimed_until <= NOW())
+		RETURNING period_start
+	`, start, end, int64(lease.Seconds()))
+	if err := row.Scan(&claimed); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *Store) FinishReport(ctx context.Context, start, end time.Time, sent bool) error {
+	// 投递成功记录 sent_at；失败时释放租约，等待重试
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE stats_reports
+		SET sent_at = CASE WHEN $3 THEN NOW() END,
+			claimed_until = NULL
+		WHERE period_start = $1 AND period_end = $2
+	`, start, end, sent)
+	return err
+}
+
+// Reporter 在每个周期结束后生成该周期的报表并交给 sink 投递
+type Reporter struct {
+	store    *Store
+	sink     ReportSink
+	format   string
+	location *time.Location
+	interval time.Duration
+	logger   *log.Logger
+}
+
+func NewReporter(store *Store, sink ReportSink, format string, location *time.Location, interval time.Duration, logger *log.Logger) (*Reporter, error) {
+	// 启动时校验格式，避免配置错误到第一次生成报表时才暴露
+	if _, err := renderReport(Report{}, format); err != nil {
+		return nil, err
+	}
+	return &Reporter{
+		store:    store,
+		sink:     sink,
+		format:   format,
+		location: location,
+		interval: interval,
+		logger:   logger,
+	}, nil
+}
+
+func (r *Reporter) Run(ctx context.Context) {
+	// 启动时先补发最近一个已结束但未发送的周期，之后在每个周期边界触发，直到 ctx 被取消
+	for {
+		start, end := reportPeriod(time.Now(), r.interval, r.location)
+		wait := time.Until(end.Add(r.interval))
+		if err := r.sendPeriod(ctx, start, end); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			r.logger.Printf("stats report error: %v", err)
+			wait = min(wait, reportRetryDelay)
+		}
+
+		timer := time.NewTimer(max(wait, time.Second))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+func (r *Reporter) sendPeriod(ctx context.Context, start, end time.Time) error {
+	// 先认领再投递，同一周期只会有一个副本发送
+	claimed, err := r.store.ClaimReport(ctx, start, end, reportClaimLease)
+	if err != nil || !claimed {
+		return err
+	}
+	sendErr := r.Send(ctx, start, end)
+	if err := r.store.FinishReport(context.WithoutCancel(ctx), start, end, sendErr == nil); err != nil && sendErr == nil {
+		return err
+	}
+	return sendErr
+}
+
+func (r *Reporter) Send(ctx context.Context, start, end time.Time) error {
+	report, err := r.store.BuildReport(ctx, start, end, r.location)
+	if err != nil {
+		return err
+	}
+	rendered, err := renderReport(report, r.format)
+	if err != nil {
+		return err
+	}
+	if err := r.sink.Deliver(ctx, rendered); err != nil {
+		return err
+	}
+	r.logger.Printf("stats report delivered: %s", rendered.Filename)
+	return nil
+}
@@ -0,0 +1,95 @@
+package stats
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderReportCSV(t *testing.T) {
+	start := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	report := Report{
+		PeriodStart: start,
+		PeriodEnd:   start.AddDate(0, 0, 2),
+		Created:     5,
+		Completed:   3,
+		Days: []Bucket{
+			{Start: start, Created: 2, Completed: 1},
+			{Start: start.AddDate(0, 0, 1), Created: 3, Completed: 2},
+		},
+	}
+
+	rendered, err := renderReport(report, "csv")
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if rendered.Filename != "stats-report-2024-03-04-2024-03-06.csv" {
+		t.Fatalf("unexpected filename: %s", rendered.Filename)
+	}
+	want := "date,created,completed\n2024-03-04,2,1\n2024-03-05,3,2\ntotal,5,3\n"
+	if string(rendered.Body) != want {
+		t.Fatalf("unexpected body:\n%s", rendered.Body)
+	}
+}
+
+func TestRenderReportText(t *testing.T) {
+	start := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	report := Report{
+		PeriodStart: start,
+		PeriodEnd:   start.AddDate(0, 0, 7),
+		Summary:     Summary{Total: 10, Done: 4},
+		Created:     6,
+	}
+
+	rendered, err := renderReport(report, "text")
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	body := string(rendered.Body)
+	if !strings.Contains(body, "Created:   6") || !strings.Contains(body, "10 total, 4 done") {
+		t.Fatalf("unexpected body:\n%s", body)
+	}
+}
+
+func TestRenderReportUnknownFormat(t *testing.T) {
+	if _, err := renderReport(Report{}, "xml"); err == nil {
+		t.Fatal("expected error for unknown format")
+	}
+}
+
+func TestReportPeriodAlignsToWeeks(t *testing.T) {
+	shanghai := time.FixedZone("UTC+8", 8*3600)
+	// 2024-03-13 是周三，上一个完整的自然周是 03-04（周一）到 03-11
+	now := time.Date(2024, 3, 13, 15, 30, 0, 0, shanghai)
+	start, end := reportPeriod(now, 7*24*time.Hour, shanghai)
+	if !start.Equal(time.Date(2024, 3, 4, 0, 0, 0, 0, shanghai)) || !end.Equal(time.Date(2024, 3, 11, 0, 0, 0, 0, shanghai)) {
+		t.Fatalf("unexpected week: %s - %s", start, end)
+	}
+
+	// 正好落在周一 0 点时，刚结束的一周即为上一周期
+	start, end = reportPeriod(time.Date(2024, 3, 11, 0, 0, 0, 0, shanghai), 7*24*time.Hour, shanghai)
+	if !start.Equal(time.Date(2024, 3, 4, 0, 0, 0, 0, shanghai)) || !end.Equal(time.Date(2024, 3, 11, 0, 0, 0, 0, shanghai)) {
+		t.Fatalf("unexpected week at boundary: %s - %s", start, end)
+	}
+}
+
+func TestReportPeriodDaysAcrossDST(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("tzdata unavailable: %v", err)
+	}
+	// 2024-03-10 夏令时开始，这一天只有 23 小时，周期仍以当地零点为界
+	now := time.Date(2024, 3, 11, 9, 0, 0, 0, newYork)
+	start, end := reportPeriod(now, 24*time.Hour, newYork)
+	if !start.Equal(time.Date(2024, 3, 10, 0, 0, 0, 0, newYork)) || !end.Equal(time.Date(2024, 3, 11, 0, 0, 0, 0, newYork)) {
+		t.Fatalf("unexpected day: %s - %s", start, end)
+	}
+}
+
+func TestReportPeriodHours(t *testing.T) {
+	now := time.Date(2024, 3, 13, 15, 30, 0, 0, time.UTC)
+	start, end := reportPeriod(now, 6*time.Hour, time.UTC)
+	if !start.Equal(time.Date(2024, 3, 13, 6, 0, 0, 0, time.UTC)) || !end.Equal(time.Date(2024, 3, 13, 12, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected period: %s - %s", start, end)
+	}
+}
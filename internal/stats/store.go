@@ -3,15 +3,44 @@ package stats
 import (
 	"context"
 	"database/sql"
+	"time"
+
+	"go_test/internal/metrics"
 )
 
+// Summary 统计全部 todo（包括已归档的），Archived 单独给出归档数量，AsOf 为数据对应的时间点
 type Summary struct {
-	Total int64 `json:"total"`
-	Done  int64 `json:"done"`
+	Total    int64     `json:"total"`
+	Done     int64     `json:"done"`
+	Archived int64     `json:"archived"`
+	AsOf     time.Time `json:"as_of"`
+}
+
+func (s Summary) counts() Summary {
+	// 去掉 AsOf 后的计数部分，用于计算 ETag：计数不变时缓存刷新也不会让 ETag 失效
+	s.AsOf = time.Time{}
+	return s
+}
+
+// Bucket 是时间序列中的一个区间，Start 为该区间在所选时区内的起点
+type Bucket struct {
+	Start     time.Time `json:"start"`
+	Created   int64     `json:"created"`
+	Completed int64     `json:"completed"`
+}
+
+// TimeseriesQuery 描述按时间分桶统计的范围，To 为开区间
+type TimeseriesQuery struct {
+	From     time.Time
+	To       time.Time
+	Interval string
+	Location *time.Location
 }
 
 type Store struct {
 	db *sql.DB
+	// replica 为 nil 时所有查询都走主库
+	replica *replicaState
 }
 
 func NewStore(db *sql.DB) *Store {
@@ -20,15 +49,94 @@ func NewStore(db *sql.DB) *Store {
 }
 
 func (s *Store) Summary(ctx context.Context) (Summary, error) {
-	// 汇总 todo 统计信息
-	var summary Summary
-	row := s.db.QueryRowContext(ctx, `
-		SELECT COUNT(*) AS total,
-			COALESCE(SUM(CASE WHEN done THEN 1 ELSE 0 END), 0) AS done
-		FROM todos
-	`)
-	if err := row.Scan(&summary.Total, &summary.Done); err != nil {
+	// 汇总 todo 统计信息：对触发器维护的计数分片求和，分片表尚未创建或初始化时回退到全表统计
+	summary, err := read(ctx, s, func(db *sql.DB) (Summary, error) {
+		var summary Summary
+		var shards int
+		row := db.QueryRowContext(ctx, `
+			SELECT COALESCE(SUM(total), 0), COALESCE(SUM(done), 0), COALESCE(SUM(archived), 0), COUNT(*)
+			FROM todo_counter_shards
+		`)
+		if err := row.Scan(&summary.Total, &summary.Done, &summary.Archived, &shards); err != nil {
+			if isUndefinedTable(err) {
+				return exactSummary(ctx, db)
+			}
+			return Summary{}, err
+		}
+		if shards == 0 {
+			return exactSummary(ctx, db)
+		}
+		return summary, nil
+	})
+	if err != nil {
 		return Summary{}, err
 	}
+	summary.AsOf = s.AsOf(time.Now())
 	return summary, nil
 }
+
+func (s *Store) Timeseries(ctx context.Context, query TimeseriesQuery) ([]Bucket, error) {
+	// 按 day/week/month 分桶统计新建数与完成数；generate_series 补齐没有数据的区间，
+	// 分桶在调用方时区内进行，完成时间以 completed_at 为准
+	return read(ctx, s, func(db *sql.DB) ([]Bucket, error) {
+		rows, err := db.QueryContext(ctx, `
+			WITH buckets AS (
+				SELECT generate_series(
+					date_trunc($3, $1::timestamptz AT TIME ZONE $4),
+					date_trunc($3, ($2::timestamptz - INTERVAL '1 microsecond') AT TIME ZONE $4),
+					('1 ' || $3)::interval
+				) AS bucket
+			),
+			created AS (
+				SELECT date_trunc($3, created_at AT TIME ZONE $4) AS bucket, COUNT(*) AS count
+				FROM todos
+				WHERE created_at >= $1 AND created_at < $2
+				GROUP BY 1
+			),
+			completed AS (
+				SELECT date_trunc($3, completed_at AT TIME ZONE $4) AS bucket, COUNT(*) AS count
+				FROM todos
+				WHERE completed_at >= $1 AND completed_at < $2
+				GROUP BY 1
+			)
+			SELECT b.bucket AT TIME ZONE $4,
+				COALESCE(c.count, 0),
+				COALESCE(d.count, 0)
+			FROM buckets b
+			LEFT JOIN created c ON c.bucket = b.bucket
+			LEFT JOIN completed d ON d.bucket = b.bucket
+			ORDER BY b.bucket
+		`, query.From, query.To, query.Interval, query.Location.String())
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		buckets := []Bucket{}
+		for rows.Next() {
+			var bucket Bucket
+			if err := rows.Scan(&bucket.Start, &bucket.Created, &bucket.Completed); err != nil {
+				return nil, err
+			}
+			bucket.Start = bucket.Start.In(query.Location)
+			buckets = append(buckets, bucket)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return buckets, nil
+	})
+}
+
+func (s *Store) collectMetrics(ctx context.Context) ([]metrics.Sample, error) {
+	// 业务指标：todo 总数与完成数
+	summary, err := s.Summary(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return []metrics.Sample{
+		{Name: "todos_total", Help: "Number of todos, including archived ones.", Type: metrics.TypeGauge, Value: float64(summary.Total)},
+		{Name: "todos_done", Help: "Number of todos marked done.", Type: metrics.TypeGauge, Value: float64(summary.Done)},
+		{Name: "todos_archived", Help: "Number of archived todos.", Type: metrics.TypeGauge, Value: float64(summary.Archived)},
+	}, nil
+}
@@ -0,0 +1,139 @@
+package stats
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// UserActivityBucket 是 /stats/users 时间序列中的一个区间，活跃用户指区间内登录过的去重用户数
+type UserActivityBucket struct {
+	Start          time.Time `json:"start"`
+	Signups        int64     `json:"signups"`
+	ActiveUsers    int64     `json:"active_users"`
+	PasswordResets int64     `json:"password_resets"`
+}
+
+// ActiveUsers 是截至当前的最近 1/7/30 天登录过的去重用户数
+type ActiveUsers struct {
+	Daily   int64 `json:"daily"`
+	Weekly  int64 `json:"weekly"`
+	Monthly int64 `json:"monthly"`
+}
+
+// TodoDistribution 是每个用户拥有的 todo 数量分布，没有 todo 的用户按 0 计入
+type TodoDistribution struct {
+	Users int64   `json:"users"`
+	Mean  float64 `json:"mean"`
+	P50   float64 `json:"p50"`
+	P75   float64 `json:"p75"`
+	P90   float64 `json:"p90"`
+	P99   float64 `json:"p99"`
+	Max   int64   `json:"max"`
+}
+
+func (s *Store) UserActivity(ctx context.Context, query TimeseriesQuery) ([]UserActivityBucket, error) {
+	// 与 Timeseries 相同的分桶方式：注册按 users.created_at，活跃按会话创建（登录）时间，
+	// 重置请求按 password_resets.created_at（已使用的记录也会保留）
+	return read(ctx, s, func(db *sql.DB) ([]UserActivityBucket, error) {
+		rows, err := db.QueryContext(ctx, `
+			WITH buckets AS (
+				SELECT generate_series(
+					date_trunc($3, $1::timestamptz AT TIME ZONE $4),
+					date_trunc($3, ($2::timestamptz - INTERVAL '1 microsecond') AT TIME ZONE $4),
+					('1 ' || $3)::interval
+				) AS bucket
+			),
+			signups AS (
+				SELECT date_trunc($3, created_at AT TIME ZONE $4) AS bucket, COUNT(*) AS count
+				FROM users
+				WHERE created_at >= $1 AND created_at < $2
+				GROUP BY 1
+			),
+			active AS (
+				SELECT date_trunc($3, created_at AT TIME ZONE $4) AS bucket, COUNT(DISTINCT user_id) AS count
+				FROM user_sessions
+				WHERE created_at >= $1 AND created_at < $2
+				GROUP BY 1
+			),
+			resets AS (
+				SELECT date_trunc($3, created_at AT TIME ZONE $4) AS bucket, COUNT(*) AS count
+				FROM password_resets
+				WHERE created_at >= $1 AND created_at < $2
+				GROUP BY 1
+			)
+			SELECT b.bucket AT TIME ZONE $4,
+				COALESCE(s.count, 0),
+				COALESCE(a.count, 0),
+				COALESCE(r.count, 0)
+			FROM buckets b
+			LEFT JOIN signups s ON s.bucket = b.bucket
+			LEFT JOIN active a ON a.bucket = b.bucket
+			LEFT JOIN resets r ON r.bucket = b.bucket
+			ORDER BY b.bucket
+		`, query.From, query.To, query.Interval, query.Location.String())
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		buckets := []UserActivityBucket{}
+		for rows.Next() {
+			var bucket UserActivityBucket
+			if err := rows.Scan(&bucket.Start, &bucket.Signups, &bucket.ActiveUsers, &bucket.PasswordResets); err != nil {
+				return nil, err
+			}
+			bucket.Start = bucket.Start.In(query.Location)
+			buckets = append(buckets, bucket)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return buckets, nil
+	})
+}
+
+func (s *Store) ActiveUsers(ctx context.Context) (ActiveUsers, error) {
+	// DAU/WAU/MAU：按滚动窗口统计，一次扫描最近 30 天的会话
+	return read(ctx, s, func(db *sql.DB) (ActiveUsers, error) {
+		var active ActiveUsers
+		row := db.QueryRowContext(ctx, `
+			SELECT COUNT(DISTINCT user_id) FILTER (WHERE created_at >= NOW() - INTERVAL '1 day'),
+				COUNT(DISTINCT user_id) FILTER (WHERE created_at >= NOW() - INTERVAL '7 days'),
+				COUNT(DISTINCT user_id)
+			FROM user_sessions
+			WHERE created_at >= NOW() - INTERVAL '30 days'
+		`)
+		if err := row.Scan(&active.Daily, &active.Weekly, &active.Monthly); err != nil {
+			return ActiveUsers{}, err
+		}
+		return active, nil
+	})
+}
+
+func (s *Store) TodoDistribution(ctx context.Context) (TodoDistribution, error) {
+	// 百分位用 percentile_cont 在数据库中计算，没有用户时各项为 0
+	return read(ctx, s, func(db *sql.DB) (TodoDistribution, error) {
+		var dist TodoDistribution
+		row := db.QueryRowContext(ctx, `
+			WITH per_user AS (
+				SELECT u.id, COUNT(t.id) AS todos
+				FROM users u
+				LEFT JOIN todos t ON t.user_id = u.id
+				GROUP BY u.id
+			)
+			SELECT COUNT(*),
+				COALESCE(ROUND(AVG(todos), 2), 0)::float8,
+				COALESCE(percentile_cont(0.50) WITHIN GROUP (ORDER BY todos), 0),
+				COALESCE(percentile_cont(0.75) WITHIN GROUP (ORDER BY todos), 0),
+				COALESCE(percentile_cont(0.90) WITHIN GROUP (ORDER BY todos), 0),
+				COALESCE(percentile_cont(0.99) WITHIN GROUP (ORDER BY todos), 0),
+				COALESCE(MAX(todos), 0)
+			FROM per_user
+		`)
+		if err := row.Scan(&dist.Users, &dist.Mean, &dist.P50, &dist.P75, &dist.P90, &dist.P99, &dist.Max); err != nil {
+			return TodoDistribution{}, err
+		}
+		return dist, nil
+	})
+}
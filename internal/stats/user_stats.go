@@ -0,0 +1,186 @@
+package stats
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// UserSummary 是单个用户的个人统计
+type UserSummary struct {
+	UserID             int64     `json:"user_id"`
+	Total              int64     `json:"total"`
+	Done               int64     `json:"done"`
+	Overdue            int64     `json:"overdue"`
+	CompletionRate     float64   `json:"completion_rate"`
+	CurrentStreak      int64     `json:"current_streak_days"`
+	LongestStreak      int64     `json:"longest_streak_days"`
+	AvgHoursToComplete *float64  `json:"avg_hours_to_complete"`
+	AsOf               time.Time `json:"as_of"`
+}
+
+// ListSummary 是某个清单内的统计，未归入清单的 todo 的 List 为空串
+type ListSummary struct {
+	List           string  `json:"list"`
+	Total          int64   `json:"total"`
+	Done           int64   `json:"done"`
+	Overdue        int64   `json:"overdue"`
+	CompletionRate float64 `json:"completion_rate"`
+}
+
+// UserBreakdown 是管理员视角下每个用户的汇总
+type UserBreakdown struct {
+	UserID             int64    `json:"user_id"`
+	Email              string   `json:"email"`
+	Total              int64    `json:"total"`
+	Done               int64    `json:"done"`
+	Overdue            int64    `json:"overdue"`
+	CompletionRate     float64  `json:"completion_rate"`
+	AvgHoursToComplete *float64 `json:"avg_hours_to_complete"`
+}
+
+func (s *Store) UserSummary(ctx context.Context, userID int64, location *time.Location) (UserSummary, error) {
+	// 个人统计全部在 SQL 中完成：连续完成天数用“日期减行号”的 gaps-and-islands 写法，
+	// 日期按调用方时区划分，当前连续天数允许停在昨天（今天还没完成不算中断）
+	summary, err := read(ctx, s, func(db *sql.DB) (UserSummary, error) {
+		summary := UserSummary{UserID: userID}
+		var avgSeconds *float64
+		row := db.QueryRowContext(ctx, `
+			WITH mine AS (
+				SELECT status, due_at, created_at, completed_at
+				FROM todos
+				WHERE user_id = $1
+			),
+			totals AS (
+				SELECT COUNT(*) AS total,
+					COUNT(*) FILTER (WHERE status = 'done') AS done,
+					COUNT(*) FILTER (WHERE status NOT IN ('done', 'cancelled') AND due_at < NOW()) AS overdue,
+					AVG(EXTRACT(EPOCH FROM completed_at - created_at)) FILTER (WHERE completed_at IS NOT NULL) AS avg_seconds
+				FROM mine
+			),
+			days AS (
+				SELECT DISTINCT (completed_at AT TIME ZONE $2)::date AS day
+				FROM mine
+				WHERE completed_at IS NOT NULL
+			),
+			islands AS (
+				SELECT day, day - (ROW_NUMBER() OVER (ORDER BY day))::int AS grp
+				FROM days
+			),
+			streaks AS (
+				SELECT MAX(day) AS end_day, COUNT(*) AS length
+				FROM islands
+				GROUP BY grp
+			)
+			SELECT t.total, t.done, t.overdue, t.avg_seconds::float8,
+				COALESCE((SELECT MAX(length) FROM streaks), 0),
+				COALESCE((
+					SELECT length
+					FROM streaks
+					WHERE end_day >= (NOW() AT TIME ZONE $2)::date - 1
+					ORDER BY end_day DESC
+					LIMIT 1
+				), 0)
+			FROM totals t
+		`, userID, location.String())
+		if err := row.Scan(&summary.Total, &summary.Done, &summary.Overdue, &avgSeconds,
+			&summary.LongestStreak, &summary.CurrentStreak); err != nil {
+			return UserSummary{}, err
+		}
+		summary.CompletionRate = completionRate(summary.Done, summary.Total)
+		summary.AvgHoursToComplete = secondsToHours(avgSeconds)
+		return summary, nil
+	})
+	if err != nil {
+		return UserSummary{}, err
+	}
+	summary.AsOf = s.AsOf(time.Now())
+	return summary, nil
+}
+
+func (s *Store) UserListSummaries(ctx context.Context, userID int64) ([]ListSummary, error) {
+	// 按清单拆分个人统计
+	return read(ctx, s, func(db *sql.DB) ([]ListSummary, error) {
+		rows, err := db.QueryContext(ctx, `
+			SELECT COALESCE(list_name, ''),
+				COUNT(*),
+				COUNT(*) FILTER (WHERE status = 'done'),
+				COUNT(*) FILTER (WHERE status NOT IN ('done', 'cancelled') AND due_at < NOW())
+			FROM todos
+			WHERE user_id = $1
+			GROUP BY 1
+			ORDER BY 1
+		`, userID)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		lists := []ListSummary{}
+		for rows.Next() {
+			var item ListSummary
+			if err := rows.Scan(&item.List, &item.Total, &item.Done, &item.Overdue); err != nil {
+				return nil, err
+			}
+			item.CompletionRate = completionRate(item.Done, item.Total)
+			lists = append(lists, item)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return lists, nil
+	})
+}
+
+func (s *Store) UserBreakdown(ctx context.Context, limit, offset int) ([]UserBreakdown, error) {
+	// 管理员按用户查看汇总，分页按用户 ID 升序
+	return read(ctx, s, func(db *sql.DB) ([]UserBreakdown, error) {
+		rows, err := db.QueryContext(ctx, `
+			SELECT u.id, u.email,
+				COUNT(t.id),
+				COUNT(t.id) FILTER (WHERE t.status = 'done'),
+				COUNT(t.id) FILTER (WHERE t.status NOT IN ('done', 'cancelled') AND t.due_at < NOW()),
+				(AVG(EXTRACT(EPOCH FROM t.completed_at - t.created_at)) FILTER (WHERE t.completed_at IS NOT NULL))::float8
+			FROM users u
+			LEFT JOIN todos t ON t.user_id = u.id
+			GROUP BY u.id, u.email
+			ORDER BY u.id
+			LIMIT $1 OFFSET $2
+		`, limit, offset)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		items := []UserBreakdown{}
+		for rows.Next() {
+			var item UserBreakdown
+			var avgSeconds *float64
+			if err := rows.Scan(&item.UserID, &item.Email, &item.Total, &item.Done, &item.Overdue, &avgSeconds); err != nil {
+				return nil, err
+			}
+			item.CompletionRate = completionRate(item.Done, item.Total)
+			item.AvgHoursToComplete = secondsToHours(avgSeconds)
+			items = append(items, item)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return items, nil
+	})
+}
+
+func completionRate(done, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(done) / float64(total)
+}
+
+func secondsToHours(seconds *float64) *float64 {
+	if seconds == nil {
+		return nil
+	}
+	hours := *seconds / 3600
+	return &hours
+}
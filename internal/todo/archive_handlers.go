@@ -0,0 +1,92 @@
+package todo
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+)
+
+func (h *Handler) handleArchiveTodo(w http.ResponseWriter, r *http.Request) {
+	// 手动归档
+	h.setArchived(w, r, true)
+}
+
+func (h *Handler) handleUnarchiveTodo(w http.ResponseWriter, r *http.Request) {
+	// 取消归档
+	h.setArchived(w, r, false)
+}
+
+func (h *Handler) setArchived(w http.ResponseWriter, r *http.Request, archived bool) {
+	id, err := readIDParam(r)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	todo, err := h.store.SetArchived(r.Context(), id, archived)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.writeError(w, http.StatusNotFound, "todo not found")
+			return
+		}
+		h.writeError(w, http.StatusInternalServerError, "failed to update todo")
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, todo)
+}
+
+func (h *Handler) handleGetArchivePolicy(w http.ResponseWriter, r *http.Request) {
+	// 查询当前用户的自动归档规则
+	userID := *ownerFromRequest(r)
+	policy, err := h.store.GetArchivePolicy(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.writeError(w, http.StatusNotFound, "archive policy not found")
+			return
+		}
+		h.writeError(w, http.StatusInternalServerError, "failed to load archive policy")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, policy)
+}
+
+func (h *Handler) handleSaveArchivePolicy(w http.ResponseWriter, r *http.Request) {
+	// 设置当前用户的自动归档规则：完成超过 after_days 天的 todo 会被后台任务归档
+	var input archivePolicyRequest
+	if err := h.decodeJSON(w, r, &input); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if input.AfterDays < 1 {
+		h.writeError(w, http.StatusBadRequest, "after_days must be at least 1")
+		return
+	}
+	enabled := true
+	if input.Enabled != nil {
+		enabled = *input.Enabled
+	}
+
+	userID := *ownerFromRequest(r)
+	policy, err := h.store.SaveArchivePolicy(r.Context(), userID, enabled, input.AfterDays)
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to save archive policy")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, policy)
+}
+
+func (h *Handler) handleDeleteArchivePolicy(w http.ResponseWriter, r *http.Request) {
+	// 删除规则
+	userID := *ownerFromRequest(r)
+	deleted, err := h.store.DeleteArchivePolicy(r.Context(), userID)
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to delete archive policy")
+		return
+	}
+	if !deleted {
+		h.writeError(w, http.StatusNotFound, "archive policy not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
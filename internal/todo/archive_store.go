@@ -0,0 +1,82 @@
+package todo
+
+import "context"
+
+func (s *Store) SetArchived(ctx context.Context, id int64, archived bool) (Todo, error) {
+	// 手动归档或取消归档；重复归档保留最初的归档时间
+	row := s.db.QueryRowContext(ctx, `
+		UPDATE todos
+		SET archived_at = CASE WHEN $1 THEN COALESCE(archived_at, NOW()) END,
+			updated_at = NOW()
+		WHERE id = $2
+		RETURNING `+todoColumns+`
+	`, archived, id)
+	return scanTodo(row)
+}
+
+func (s *Store) GetArchivePolicy(ctx context.Context, userID int64) (ArchivePolicy, error) {
+	// 查询用户的自动归档规则
+	var policy ArchivePolicy
+	row := s.db.QueryRowContext(ctx, `
+		SELECT user_id, enabled, after_days, updated_at
+		FROM archive_policies
+		WHERE user_id = $1
+	`, userID)
+	if err := row.Scan(&policy.UserID, &policy.Enabled, &policy.AfterDays, &policy.UpdatedAt); err != nil {
+		return ArchivePolicy{}, err
+	}
+	return policy, nil
+}
+
+func (s *Store) SaveArchivePolicy(ctx context.Context, userID int64, enabled bool, afterDays int) (ArchivePolicy, error) {
+	// 新建或覆盖用户的自动归档规则
+	var policy ArchivePolicy
+	row := s.db.QueryRowContext(ctx, `
+		INSERT INTO archive_policies (user_id, enabled, after_days)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET enabled = EXCLUDED.enabled,
+			after_days = EXCLUDED.after_days,
+			updated_at = NOW()
+		RETURNING user_id, enabled, after_days, updated_at
+	`, userID, enabled, afterDays)
+	if err := row.Scan(&policy.UserID, &policy.Enabled, &policy.AfterDays, &policy.UpdatedAt); err != nil {
+		return ArchivePolicy{}, err
+	}
+	return policy, nil
+}
+
+func (s *Store) DeleteArchivePolicy(ctx context.Context, userID int64) (bool, error) {
+	// 删除规则即关闭自动归档
+	res, err := s.db.ExecContext(ctx, `
+		DELETE FROM archive_policies
+		WHERE user_id = $1
+	`, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+func (s *Store) ApplyArchivePolicies(ctx context.Context) (int64, error) {
+	// 按每个用户的规则批量归档已结束的 todo；取消的 todo 没有 completed_at，按最后更新时间计算
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE todos t
+		SET archived_at = NOW(),
+			updated_at = NOW()
+		FROM archive_policies p
+		WHERE p.user_id = t.user_id
+			AND p.enabled
+			AND t.archived_at IS NULL
+			AND t.status IN ('done', 'cancelled')
+			AND COALESCE(t.completed_at, t.updated_at) < NOW() - p.after_days * INTERVAL '1 day'
+	`)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
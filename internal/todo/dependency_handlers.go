@@ -0,0 +1,128 @@
+package todo
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+func (h *Handler) handleListBlockers(w http.ResponseWriter, r *http.Request) {
+	// 某个 todo 的直接前置任务
+	id, err := readIDParam(r)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	if _, err := h.store.Get(r.Context(), id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.writeError(w, http.StatusNotFound, "todo not found")
+			return
+		}
+		h.writeError(w, http.StatusInternalServerError, "failed to load todo")
+		return
+	}
+
+	items, err := h.store.ListBlockers(r.Context(), id)
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to load blockers")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, map[string]any{"blockers": items})
+}
+
+func (h *Handler) handleAddBlocker(w http.ResponseWriter, r *http.Request) {
+	// 声明 todo 被 blocker_id 阻塞，拒绝会形成环的依赖
+	id, err := readIDParam(r)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var input addBlockerRequest
+	if err := h.decodeJSON(w, r, &input); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if input.BlockerID < 1 {
+		h.writeError(w, http.StatusBadRequest, "invalid blocker_id")
+		return
+	}
+	if input.BlockerID == id {
+		h.writeError(w, http.StatusBadRequest, "todo cannot block itself")
+		return
+	}
+
+	if err := h.store.AddBlocker(r.Context(), id, input.BlockerID); err != nil {
+		if errors.Is(err, ErrTodoNotFound) {
+			h.writeError(w, http.StatusNotFound, "todo not found")
+			return
+		}
+		if errors.Is(err, ErrDependencyCycle) {
+			h.writeError(w, http.StatusConflict, "dependency would create a cycle")
+			return
+		}
+		h.writeError(w, http.StatusInternalServerError, "failed to add blocker")
+		return
+	}
+
+	items, err := h.store.ListBlockers(r.Context(), id)
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to load blockers")
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, map[string]any{"blockers": items})
+}
+
+func (h *Handler) handleRemoveBlocker(w http.ResponseWriter, r *http.Request) {
+	// 移除依赖
+	id, err := readIDParam(r)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	blockerID, err := readInt64Param(r, "blockerID")
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid blocker id")
+		return
+	}
+
+	removed, err := h.store.RemoveBlocker(r.Context(), id, blockerID)
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to remove blocker")
+		return
+	}
+	if !removed {
+		h.writeError(w, http.StatusNotFound, "dependency not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) handleTopologicalExport(w http.ResponseWriter, r *http.Request) {
+	// 按依赖关系导出清单：每个 todo 都排在它的前置任务之后
+	list := strings.TrimSpace(r.URL.Query().Get("list"))
+	if list == "" {
+		h.writeError(w, http.StatusBadRequest, "list is required")
+		return
+	}
+
+	todos, edges, err := h.store.ListDependencies(r.Context(), listFilter{List: &list})
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to load todos")
+		return
+	}
+
+	ordered, err := topologicalOrder(todos, edges)
+	if err != nil {
+		h.writeError(w, http.StatusConflict, "dependency cycle detected")
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, map[string]any{
+		"list":  list,
+		"todos": ordered,
+	})
+}
@@ -0,0 +1,143 @@
+package todo
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrTodoNotFound    = errors.New("todo not found")
+	ErrDependencyCycle = errors.New("dependency would create a cycle")
+)
+
+// dependencyLockKey 是依赖图写入时使用的 advisory lock，串行化并发插入以保证环检测可靠
+const dependencyLockKey = 7_202_901
+
+// dependency 表示 TodoID 被 BlockerID 阻塞
+type dependency struct {
+	TodoID    int64
+	BlockerID int64
+}
+
+func (s *Store) ListBlockers(ctx context.Context, todoID int64) ([]Todo, error) {
+	// 查询直接阻塞某个 todo 的前置任务
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+prefixedTodoColumns("t")+`
+		FROM todo_dependencies d
+		JOIN todos t ON t.id = d.blocker_id
+		WHERE d.todo_id = $1
+		ORDER BY t.id
+	`, todoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var todos []Todo
+	for rows.Next() {
+		todo, err := scanTodo(rows)
+		if err != nil {
+			return nil, err
+		}
+		todos = append(todos, todo)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return todos, nil
+}
+
+func (s *Store) AddBlocker(ctx context.Context, todoID, blockerID int64) error {
+	// 新增依赖前检查 blocker 是否已经（间接）依赖 todoID，若是则会形成环
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, dependencyLockKey); err != nil {
+		return err
+	}
+
+	var cycle bool
+	row := tx.QueryRowContext(ctx, `
+		WITH RECURSIVE upstream AS (
+			SELECT blocker_id
+			FROM todo_dependencies
+			WHERE todo_id = $1
+			UNION
+			SELECT d.blocker_id
+			FROM todo_dependencies d
+			JOIN upstream u ON d.todo_id = u.blocker_id
+		)
+		SELECT EXISTS (SELECT 1 FROM upstream WHERE blocker_id = $2)
+	`, blockerID, todoID)
+	if err := row.Scan(&cycle); err != nil {
+		return err
+	}
+	if cycle {
+		return ErrDependencyCycle
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO todo_dependencies (todo_id, blocker_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`, todoID, blockerID); err != nil {
+		if isForeignKeyViolation(err) {
+			return ErrTodoNotFound
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *Store) RemoveBlocker(ctx context.Context, todoID, blockerID int64) (bool, error) {
+	// 删除依赖
+	res, err := s.db.ExecContext(ctx, `
+		DELETE FROM todo_dependencies
+		WHERE todo_id = $1 AND blocker_id = $2
+	`, todoID, blockerID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+func (s *Store) ListDependencies(ctx context.Context, filter listFilter) ([]Todo, []dependency, error) {
+	// 查询某个清单内的 todo 以及清单内部的依赖边，filter.List 必须非空
+	todos, err := s.List(ctx, filter)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT d.todo_id, d.blocker_id
+		FROM todo_dependencies d
+		JOIN todos t ON t.id = d.todo_id
+		JOIN todos b ON b.id = d.blocker_id
+		WHERE t.list_name = $1 AND b.list_name = $1
+	`, *filter.List)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var edges []dependency
+	for rows.Next() {
+		var edge dependency
+		if err := rows.Scan(&edge.TodoID, &edge.BlockerID); err != nil {
+			return nil, nil, err
+		}
+		edges = append(edges, edge)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	return todos, edges, nil
+}
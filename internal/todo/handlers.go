@@ -11,41 +11,103 @@ import (
 	"strings"
 	"time"
 
+	"go_test/internal/auth"
+	"go_test/internal/metrics"
+
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
 
 type Handler struct {
-	store  *Store
-	logger *log.Logger
+	store   *Store
+	logger  *log.Logger
+	metrics *metrics.Registry
+	auth    auth.Authenticator
+	// verifiedWrites 为 true 时邮箱未验证的登录用户只能读取
+	verifiedWrites bool
 }
 
 func NewHandler(store *Store, logger *log.Logger) *Handler {
+	registry := metrics.NewRegistry(logger)
+	if store != nil {
+		registry.Register(metrics.DBStatsCollector(store.db))
+	}
 	return &Handler{
-		store:  store,
-		logger: logger,
+		store:   store,
+		logger:  logger,
+		metrics: registry,
 	}
 }
 
+// WithAuthenticator 启用可选的 Bearer 认证：认证后创建的 todo 记录创建者，归档规则按用户生效
+func (h *Handler) WithAuthenticator(authenticator auth.Authenticator) *Handler {
+	h.auth = authenticator
+	return h
+}
+
+// WithVerifiedWrites 要求登录用户验证邮箱后才能创建或修改数据（EMAIL_VERIFICATION=limited）
+func (h *Handler) WithVerifiedWrites() *Handler {
+	h.verifiedWrites = true
+	return h
+}
+
 func (h *Handler) Routes() http.Handler {
 	// 注册路由与中间件
 	r := chi.NewRouter()
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
+	r.Use(h.metrics.Middleware)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.Timeout(30 * time.Second))
+	if h.auth != nil {
+		r.Use(auth.Middleware(h.auth))
+		if h.verifiedWrites {
+			r.Use(auth.RequireVerifiedForWrites)
+		}
+	}
 
 	r.Get("/health", h.handleHealth)
+	r.Method(http.MethodGet, "/metrics", h.metrics)
 
 	r.Route("/todos", func(r chi.Router) {
 		r.Get("/", h.handleListTodos)
 		r.Post("/", h.handleCreateTodo)
+		r.Get("/topological", h.handleTopologicalExport)
 
 		r.Route("/{id}", func(r chi.Router) {
 			r.Get("/", h.handleGetTodo)
 			r.Put("/", h.handleUpdateTodo)
 			r.Delete("/", h.handleDeleteTodo)
+			r.Post("/archive", h.handleArchiveTodo)
+			r.Post("/unarchive", h.handleUnarchiveTodo)
+
+			r.Get("/reminders", h.handleListReminders)
+			r.Post("/reminders", h.handleCreateReminder)
+			r.Delete("/reminders/{reminderID}", h.handleDeleteReminder)
+
+			r.Get("/blockers", h.handleListBlockers)
+			r.Post("/blockers", h.handleAddBlocker)
+			r.Delete("/blockers/{blockerID}", h.handleRemoveBlocker)
+		})
+	})
+
+	r.Route("/archive-policy", func(r chi.Router) {
+		r.Use(auth.Require)
+		r.Get("/", h.handleGetArchivePolicy)
+		r.Put("/", h.handleSaveArchivePolicy)
+		r.Delete("/", h.handleDeleteArchivePolicy)
+	})
+
+	r.Route("/templates", func(r chi.Router) {
+		r.Get("/", h.handleListTemplates)
+		r.Post("/", h.handleCreateTemplate)
+
+		r.Route("/{id}", func(r chi.Router) {
+			r.Get("/", h.handleGetTemplate)
+			r.Put("/", h.handleUpdateTemplate)
+			r.Delete("/", h.handleDeleteTemplate)
+			r.Post("/instantiate", h.handleInstantiateTemplate)
 		})
 	})
 
@@ -66,9 +128,39 @@ func (h *Handler) handleHealth(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) handleListTodos(w http.ResponseWriter, r *http.Request) {
-	// 列表查询
+	// 列表查询，支持 ?status=、?list=、?actionable=true 与 ?archived=true 过滤
+	query := r.URL.Query()
+	var filter listFilter
+	if value := query.Get("status"); value != "" {
+		status := Status(value)
+		if !status.Valid() {
+			h.writeError(w, http.StatusBadRequest, "invalid status")
+			return
+		}
+		filter.Status = &status
+	}
+	if value := strings.TrimSpace(query.Get("list")); value != "" {
+		filter.List = &value
+	}
+	if value := query.Get("actionable"); value != "" {
+		actionable, err := strconv.ParseBool(value)
+		if err != nil {
+			h.writeError(w, http.StatusBadRequest, "invalid actionable")
+			return
+		}
+		filter.Actionable = actionable
+	}
+	if value := query.Get("archived"); value != "" {
+		archived, err := strconv.ParseBool(value)
+		if err != nil {
+			h.writeError(w, http.StatusBadRequest, "invalid archived")
+			return
+		}
+		filter.Archived = archived
+	}
+
 	ctx := r.Context()
-	items, err := h.store.List(ctx)
+	items, err := h.store.List(ctx, filter)
 	if err != nil {
 		h.writeError(w, http.StatusInternalServerError, "failed to load todos")
 		return
@@ -111,9 +203,35 @@ func (h *Handler) handleCreateTodo(w http.ResponseWriter, r *http.Request) {
 		h.writeError(w, http.StatusBadRequest, "title is required")
 		return
 	}
+	input.List = trimmedOptional(input.List)
+	input.UserID = ownerFromRequest(r)
+	if input.Priority != nil {
+		if _, err := parsePriority(*input.Priority); err != nil {
+			h.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if input.ParentID != nil && *input.ParentID < 1 {
+		h.writeError(w, http.StatusBadRequest, "invalid parent_id")
+		return
+	}
+
+	change, err := resolveCreateStatus(input, time.Now())
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if change.Status != nil {
+		status := string(*change.Status)
+		input.Status = &status
+	}
 
 	todo, err := h.store.Create(r.Context(), input)
 	if err != nil {
+		if errors.Is(err, ErrParentNotFound) {
+			h.writeError(w, http.StatusBadRequest, "parent todo not found")
+			return
+		}
 		h.writeError(w, http.StatusInternalServerError, "failed to create todo")
 		return
 	}
@@ -135,11 +253,22 @@ func (h *Handler) handleUpdateTodo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if input.Title == nil && input.Done == nil {
-		h.writeError(w, http.StatusBadRequest, "provide title or done")
+	if input.Title == nil && input.Done == nil && input.Status == nil && input.SnoozedUntil == nil &&
+		input.List == nil && !input.DueAt.Set && input.Priority == nil {
+		h.writeError(w, http.StatusBadRequest, "provide title, done, status, snoozed_until, list, due_at or priority")
 		return
 	}
 
+	var priority *Priority
+	if input.Priority != nil {
+		parsed, err := parsePriority(*input.Priority)
+		if err != nil {
+			h.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		priority = &parsed
+	}
+
 	if input.Title != nil {
 		trimmed := strings.TrimSpace(*input.Title)
 		if trimmed == "" {
@@ -149,12 +278,37 @@ func (h *Handler) handleUpdateTodo(w http.ResponseWriter, r *http.Request) {
 		input.Title = &trimmed
 	}
 
-	todo, err := h.store.Update(r.Context(), id, input)
+	current, err := h.store.Get(r.Context(), id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			h.writeError(w, http.StatusNotFound, "todo not found")
 			return
 		}
+		h.writeError(w, http.StatusInternalServerError, "failed to load todo")
+		return
+	}
+
+	// 按状态机校验流转，旧客户端的 done 字段在这里映射为 status
+	change, err := resolveStatusChange(current.Status, input.Status, input.Done, input.SnoozedUntil, time.Now())
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	todo, err := h.store.Update(r.Context(), id, current.Status, todoChanges{
+		Title:        input.Title,
+		List:         trimmedOptional(input.List),
+		DueAt:        input.DueAt.Value,
+		ClearDueAt:   input.DueAt.Set && input.DueAt.Value == nil,
+		Status:       change.Status,
+		Priority:     priority,
+		SnoozedUntil: change.SnoozedUntil,
+	})
+	if err != nil {
+		if errors.Is(err, ErrStatusConflict) {
+			h.writeError(w, http.StatusConflict, "todo was modified concurrently, please retry")
+			return
+		}
 		h.writeError(w, http.StatusInternalServerError, "failed to update todo")
 		return
 	}
@@ -211,12 +365,37 @@ func (h *Handler) writeError(w http.ResponseWriter, status int, message string)
 	h.writeJSON(w, status, map[string]string{"error": message})
 }
 
+func ownerFromRequest(r *http.Request) *int64 {
+	// 认证用户返回其 ID，匿名请求返回 nil；只用于记录创建者，不限制可见范围
+	identity, ok := auth.FromContext(r.Context())
+	if !ok {
+		return nil
+	}
+	return &identity.UserID
+}
+
+func trimmedOptional(value *string) *string {
+	// 去除可选字符串首尾空白，空串视为未提供
+	if value == nil {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*value)
+	if trimmed == "" {
+		return nil
+	}
+	return &trimmed
+}
+
 func readIDParam(r *http.Request) (int64, error) {
 	// 解析并校验路径参数
-	idParam := chi.URLParam(r, "id")
-	id, err := strconv.ParseInt(idParam, 10, 64)
-	if err != nil || id < 1 {
-		return 0, errors.New("invalid id")
+	return readInt64Param(r, "id")
+}
+
+func readInt64Param(r *http.Request, name string) (int64, error) {
+	// 解析并校验指定名称的正整数路径参数
+	value, err := strconv.ParseInt(chi.URLParam(r, name), 10, 64)
+	if err != nil || value < 1 {
+		return 0, errors.New("invalid " + name)
 	}
-	return id, nil
+	return value, nil
 }
@@ -0,0 +1,82 @@
+package todo
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+func runEvery(ctx context.Context, interval time.Duration, fn func(ctx context.Context)) {
+	// 立即执行一次，之后按固定间隔执行，直到 ctx 被取消
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		fn(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Unsnoozer 周期性地把 snooze 到期的 todo 恢复为 open
+type Unsnoozer struct {
+	store    *Store
+	logger   *log.Logger
+	interval time.Duration
+}
+
+func NewUnsnoozer(store *Store, logger *log.Logger, interval time.Duration) *Unsnoozer {
+	return &Unsnoozer{
+		store:    store,
+		logger:   logger,
+		interval: interval,
+	}
+}
+
+func (u *Unsnoozer) Run(ctx context.Context) {
+	runEvery(ctx, u.interval, func(ctx context.Context) {
+		count, err := u.store.Unsnooze(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				u.logger.Printf("unsnooze error: %v", err)
+			}
+			return
+		}
+		if count > 0 {
+			u.logger.Printf("unsnoozed %d todos", count)
+		}
+	})
+}
+
+// Archiver 周期性地执行各用户的自动归档规则
+type Archiver struct {
+	store    *Store
+	logger   *log.Logger
+	interval time.Duration
+}
+
+func NewArchiver(store *Store, logger *log.Logger, interval time.Duration) *Archiver {
+	return &Archiver{
+		store:    store,
+		logger:   logger,
+		interval: interval,
+	}
+}
+
+func (a *Archiver) Run(ctx context.Context) {
+	runEvery(ctx, a.interval, func(ctx context.Context) {
+		count, err := a.store.ApplyArchivePolicies(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				a.logger.Printf("auto archive error: %v", err)
+			}
+			return
+		}
+		if count > 0 {
+			a.logger.Printf("auto archived %d todos", count)
+		}
+	})
+}
@@ -1,21 +1,96 @@
 package todo
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type Todo struct {
-	ID        int64     `json:"id"`
-	Title     string    `json:"title"`
-	Done      bool      `json:"done"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID           int64      `json:"id"`
+	Title        string     `json:"title"`
+	Done         bool       `json:"done"`
+	Status       Status     `json:"status"`
+	Priority     Priority   `json:"priority"`
+	SnoozedUntil *time.Time `json:"snoozed_until"`
+	CompletedAt  *time.Time `json:"completed_at"`
+	ParentID     *int64     `json:"parent_id"`
+	List         *string    `json:"list"`
+	DueAt        *time.Time `json:"due_at"`
+	UserID       *int64     `json:"user_id"`
+	ArchivedAt   *time.Time `json:"archived_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
 }
 
 type createTodoRequest struct {
-	Title string `json:"title"`
-	Done  bool   `json:"done"`
+	Title        string     `json:"title"`
+	Done         *bool      `json:"done"`
+	Status       *string    `json:"status"`
+	Priority     *string    `json:"priority"`
+	SnoozedUntil *time.Time `json:"snoozed_until"`
+	ParentID     *int64     `json:"parent_id"`
+	List         *string    `json:"list"`
+	DueAt        *time.Time `json:"due_at"`
+	// UserID 记录创建者，由认证信息填充，不接受客户端传入
+	UserID *int64 `json:"-"`
 }
 
 type updateTodoRequest struct {
-	Title *string `json:"title"`
-	Done  *bool   `json:"done"`
+	Title        *string    `json:"title"`
+	Done         *bool      `json:"done"`
+	Status       *string    `json:"status"`
+	Priority     *string    `json:"priority"`
+	SnoozedUntil *time.Time `json:"snoozed_until"`
+	List         *string    `json:"list"`
+	// DueAt 区分未传与显式 null，null 表示清除截止时间
+	DueAt optionalTime `json:"due_at"`
+}
+
+// optionalTime 记录 JSON 字段是否出现，用于区分未传与显式 null
+type optionalTime struct {
+	Set   bool
+	Value *time.Time
+}
+
+func (o *optionalTime) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	return json.Unmarshal(data, &o.Value)
+}
+
+// todoChanges 是 handler 校验完成后交给 Store 的更新内容
+type todoChanges struct {
+	Title        *string
+	List         *string
+	DueAt        *time.Time
+	ClearDueAt   bool
+	Status       *Status
+	Priority     *Priority
+	SnoozedUntil *time.Time
+}
+
+// listFilter 是 GET /todos 支持的过滤条件
+type listFilter struct {
+	Status *Status
+	List   *string
+	// Actionable 为 true 时隐藏仍有未完成前置任务的 todo
+	Actionable bool
+	// Archived 为 true 时只返回已归档的 todo，否则只返回未归档的
+	Archived bool
+}
+
+// ArchivePolicy 是用户的自动归档规则：完成超过 AfterDays 天的 todo 会被归档
+type ArchivePolicy struct {
+	UserID    int64     `json:"user_id"`
+	Enabled   bool      `json:"enabled"`
+	AfterDays int       `json:"after_days"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type archivePolicyRequest struct {
+	Enabled   *bool `json:"enabled"`
+	AfterDays int   `json:"after_days"`
+}
+
+type addBlockerRequest struct {
+	BlockerID int64 `json:"blocker_id"`
 }
@@ -0,0 +1,32 @@
+package todo
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestUpdateTodoRequestDueAt(t *testing.T) {
+	cases := []struct {
+		body  string
+		set   bool
+		value bool
+	}{
+		{`{"title":"a"}`, false, false},
+		{`{"due_at":null}`, true, false},
+		{`{"due_at":"2030-01-01T09:00:00Z"}`, true, true},
+	}
+	for _, tc := range cases {
+		var input updateTodoRequest
+		if err := json.Unmarshal([]byte(tc.body), &input); err != nil {
+			t.Fatalf("%s: %v", tc.body, err)
+		}
+		if input.DueAt.Set != tc.set || (input.DueAt.Value != nil) != tc.value {
+			t.Fatalf("%s: got %#v", tc.body, input.DueAt)
+		}
+	}
+
+	var input updateTodoRequest
+	if err := json.Unmarshal([]byte(`{"due_at":"tomorrow"}`), &input); err == nil {
+		t.Fatal("expected invalid due_at to fail")
+	}
+}
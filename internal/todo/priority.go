@@ -0,0 +1,22 @@
+package todo
+
+import "fmt"
+
+// Priority 是 todo 的优先级，stats-api 的排行榜按优先级加权计分
+type Priority string
+
+const (
+	PriorityLow    Priority = "low"
+	PriorityMedium Priority = "medium"
+	PriorityHigh   Priority = "high"
+	PriorityUrgent Priority = "urgent"
+)
+
+func parsePriority(value string) (Priority, error) {
+	switch priority := Priority(value); priority {
+	case PriorityLow, PriorityMedium, PriorityHigh, PriorityUrgent:
+		return priority, nil
+	default:
+		return "", fmt.Errorf("invalid priority %q, must be low, medium, high or urgent", value)
+	}
+}
@@ -0,0 +1,121 @@
+package todo
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+func (h *Handler) handleListReminders(w http.ResponseWriter, r *http.Request) {
+	// 某个 todo 的提醒列表
+	id, err := readIDParam(r)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	if _, err := h.store.Get(r.Context(), id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.writeError(w, http.StatusNotFound, "todo not found")
+			return
+		}
+		h.writeError(w, http.StatusInternalServerError, "failed to load todo")
+		return
+	}
+
+	items, err := h.store.ListReminders(r.Context(), id)
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to load reminders")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, map[string]any{"reminders": items})
+}
+
+func (h *Handler) handleCreateReminder(w http.ResponseWriter, r *http.Request) {
+	// 新建提醒：remind_at（绝对时间）与 offset（截止前多久）二选一
+	id, err := readIDParam(r)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var input createReminderRequest
+	if err := h.decodeJSON(w, r, &input); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	input.Offset = strings.TrimSpace(input.Offset)
+	if (input.RemindAt == nil) == (input.Offset == "") {
+		h.writeError(w, http.StatusBadRequest, "provide exactly one of remind_at or offset")
+		return
+	}
+
+	var offsetSeconds *int64
+	if input.Offset != "" {
+		offset, err := parseOffset(input.Offset)
+		if err != nil || offset < 0 {
+			h.writeError(w, http.StatusBadRequest, "invalid offset")
+			return
+		}
+		seconds := int64(offset.Seconds())
+		offsetSeconds = &seconds
+	}
+
+	// 只允许把提醒发给 todo 创建者本人，避免借提醒向任意地址发信；留空时使用默认收件人
+	recipient := strings.TrimSpace(input.Recipient)
+	if recipient != "" {
+		ownerEmail, err := h.store.TodoOwnerEmail(r.Context(), id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				h.writeError(w, http.StatusNotFound, "todo not found")
+				return
+			}
+			h.writeError(w, http.StatusInternalServerError, "failed to load todo")
+			return
+		}
+		if ownerEmail == "" || !strings.EqualFold(recipient, ownerEmail) {
+			h.writeError(w, http.StatusBadRequest, "recipient must be the todo owner's email")
+			return
+		}
+	}
+
+	reminder, err := h.store.CreateReminder(r.Context(), id, input.RemindAt, offsetSeconds, recipient)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			h.writeError(w, http.StatusNotFound, "todo not found")
+			return
+		}
+		h.writeError(w, http.StatusInternalServerError, "failed to create reminder")
+		return
+	}
+
+	h.writeJSON(w, http.StatusCreated, reminder)
+}
+
+func (h *Handler) handleDeleteReminder(w http.ResponseWriter, r *http.Request) {
+	// 删除提醒
+	id, err := readIDParam(r)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	reminderID, err := readInt64Param(r, "reminderID")
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid reminder id")
+		return
+	}
+
+	deleted, err := h.store.DeleteReminder(r.Context(), id, reminderID)
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to delete reminder")
+		return
+	}
+	if !deleted {
+		h.writeError(w, http.StatusNotFound, "reminder not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
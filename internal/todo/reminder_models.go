@@ -0,0 +1,32 @@
+package todo
+
+import "time"
+
+// Reminder 是挂在 todo 上的提醒规则：绝对时间 RemindAt 与相对截止时间的 OffsetSeconds 二选一
+type Reminder struct {
+	ID            int64      `json:"id"`
+	TodoID        int64      `json:"todo_id"`
+	RemindAt      *time.Time `json:"remind_at"`
+	OffsetSeconds *int64     `json:"offset_seconds"`
+	Recipient     string     `json:"recipient"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"last_error"`
+	SentAt        *time.Time `json:"sent_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+type createReminderRequest struct {
+	RemindAt  *time.Time `json:"remind_at"`
+	Offset    string     `json:"offset"`
+	Recipient string     `json:"recipient"`
+}
+
+// dueReminder 是调度器认领到的待发送提醒及其 todo 信息
+type dueReminder struct {
+	ID        int64
+	TodoID    int64
+	Title     string
+	DueAt     *time.Time
+	Recipient string
+	Attempts  int
+}
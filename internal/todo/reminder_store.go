@@ -0,0 +1,171 @@
+package todo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const reminderColumns = `id, todo_id, remind_at, offset_seconds, recipient, attempts, last_error, sent_at, created_at`
+
+func (s *Store) ListReminders(ctx context.Context, todoID int64) ([]Reminder, error) {
+	// 查询某个 todo 的全部提醒
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+reminderColumns+`
+		FROM todo_reminders
+		WHERE todo_id = $1
+		ORDER BY id
+	`, todoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reminders []Reminder
+	for rows.Next() {
+		reminder, err := scanReminder(rows)
+		if err != nil {
+			return nil, err
+		}
+		reminders = append(reminders, reminder)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return reminders, nil
+}
+
+func (s *Store) CreateReminder(ctx context.Context, todoID int64, remindAt *time.Time, offsetSeconds *int64, recipient string) (Reminder, error) {
+	// 新建提醒规则
+	row := s.db.QueryRowContext(ctx, `
+		INSERT INTO todo_reminders (todo_id, remind_at, offset_seconds, recipient)
+		VALUES ($1, $2, $3, $4)
+		RETURNING `+reminderColumns+`
+	`, todoID, nullableTime(remindAt), nullableInt64(offsetSeconds), recipient)
+	return scanReminder(row)
+}
+
+func (s *Store) DeleteReminder(ctx context.Context, todoID, reminderID int64) (bool, error) {
+	// 删除提醒规则
+	res, err := s.db.ExecContext(ctx, `
+		DELETE FROM todo_reminders
+		WHERE id = $1 AND todo_id = $2
+	`, reminderID, todoID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// ProcessDueReminders 认领到期提醒并逐条交给 send 处理。
+// 认领时用 FOR UPDATE SKIP LOCKED 选出提醒并写入租约 claimed_until，随即提交释放行锁；
+// 发送在事务之外进行，租约到期前其他副本不会重复认领，进程崩溃时租约过期后自动重试。
+func (s *Store) ProcessDueReminders(ctx context.Context, limit, maxAttempts int, lease time.Duration, send func(dueReminder) error) (int, error) {
+	due, err := s.claimDueReminders(ctx, limit, maxAttempts, lease)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, item := range due {
+		if sendErr := send(item); sendErr != nil {
+			// 失败时按尝试次数指数退避，超过 maxAttempts 后不再认领
+			backoff := time.Duration(1<<min(item.Attempts, 10)) * time.Minute
+			if _, err := s.db.ExecContext(ctx, `
+				UPDATE todo_reminders
+				SET attempts = attempts + 1,
+					last_error = $1,
+					next_attempt_at = NOW() + $2 * INTERVAL '1 second',
+					claimed_until = NULL
+				WHERE id = $3
+			`, sendErr.Error(), int64(backoff.Seconds()), item.ID); err != nil {
+				return sent, err
+			}
+			continue
+		}
+
+		if _, err := s.db.ExecContext(ctx, `
+			UPDATE todo_reminders
+			SET sent_at = NOW(),
+				attempts = attempts + 1,
+				last_error = '',
+				claimed_until = NULL
+			WHERE id = $1
+		`, item.ID); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+func (s *Store) claimDueReminders(ctx context.Context, limit, maxAttempts int, lease time.Duration) ([]dueReminder, error) {
+	// 单条语句完成选取与写租约，语句结束即释放行锁
+	rows, err := s.db.QueryContext(ctx, `
+		WITH due AS (
+			SELECT r.id
+			FROM todo_reminders r
+			JOIN todos t ON t.id = r.todo_id
+			WHERE r.sent_at IS NULL
+				AND t.status NOT IN ('done', 'cancelled')
+				AND r.attempts < $1
+				AND (r.next_attempt_at IS NULL OR r.next_attempt_at <= NOW())
+				AND (r.claimed_until IS NULL OR r.claimed_until <= NOW())
+				AND COALESCE(r.remind_at, t.due_at - r.offset_seconds * INTERVAL '1 second') <= NOW()
+			ORDER BY COALESCE(r.remind_at, t.due_at - r.offset_seconds * INTERVAL '1 second'), r.id
+			LIMIT $2
+			FOR UPDATE OF r SKIP LOCKED
+		)
+		UPDATE todo_reminders r
+		SET claimed_until = NOW() + $3 * INTERVAL '1 second'
+		FROM due, todos t
+		WHERE r.id = due.id AND t.id = r.todo_id
+		RETURNING r.id, r.todo_id, t.title, t.due_at, r.recipient, r.attempts
+	`, maxAttempts, limit, int64(lease.Seconds()))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var due []dueReminder
+	for rows.Next() {
+		var item dueReminder
+		if err := rows.Scan(&item.ID, &item.TodoID, &item.Title, &item.DueAt, &item.Recipient, &item.Attempts); err != nil {
+			return nil, err
+		}
+		due = append(due, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return due, nil
+}
+
+func (s *Store) TodoOwnerEmail(ctx context.Context, todoID int64) (string, error) {
+	// 查询 todo 创建者的邮箱，匿名创建的 todo 返回空串
+	var email sql.NullString
+	row := s.db.QueryRowContext(ctx, `
+		SELECT u.email
+		FROM todos t
+		LEFT JOIN users u ON u.id = t.user_id
+		WHERE t.id = $1
+	`, todoID)
+	if err := row.Scan(&email); err != nil {
+		return "", err
+	}
+	return email.String, nil
+}
+
+func scanReminder(row rowScanner) (Reminder, error) {
+	// 按 reminderColumns 的顺序扫描一行
+	var reminder Reminder
+	if err := row.Scan(&reminder.ID, &reminder.TodoID, &reminder.RemindAt, &reminder.OffsetSeconds, &reminder.Recipient,
+		&reminder.Attempts, &reminder.LastError, &reminder.SentAt, &reminder.CreatedAt); err != nil {
+		return Reminder{}, err
+	}
+	return reminder, nil
+}
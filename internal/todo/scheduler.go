@@ -0,0 +1,83 @@
+package todo
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go_test/internal/notify"
+)
+
+const (
+	reminderBatchSize   = 50
+	reminderMaxAttempts = 5
+	reminderSendTimeout = 15 * time.Second
+	// reminderLease 覆盖一整批逐条发送的最长耗时，租约内其他副本不会重复认领
+	reminderLease = reminderBatchSize*reminderSendTimeout + time.Minute
+)
+
+// ReminderScheduler 周期性地认领到期提醒并通过 Notifier 发送
+type ReminderScheduler struct {
+	store    *Store
+	notifier notify.Notifier
+	logger   *log.Logger
+	interval time.Duration
+}
+
+func NewReminderScheduler(store *Store, notifier notify.Notifier, logger *log.Logger, interval time.Duration) *ReminderScheduler {
+	return &ReminderScheduler{
+		store:    store,
+		notifier: notifier,
+		logger:   logger,
+		interval: interval,
+	}
+}
+
+func (s *ReminderScheduler) Run(ctx context.Context) {
+	// 按固定间隔轮询，直到 ctx 被取消
+	runEvery(ctx, s.interval, s.runOnce)
+}
+
+func (s *ReminderScheduler) runOnce(ctx context.Context) {
+	// 一批处理满时立刻继续下一批，避免积压等到下一个周期
+	for {
+		sent, err := s.store.ProcessDueReminders(ctx, reminderBatchSize, reminderMaxAttempts, reminderLease, func(item dueReminder) error {
+			return s.send(ctx, item)
+		})
+		if err != nil {
+			if ctx.Err() == nil {
+				s.logger.Printf("reminder scheduler error: %v", err)
+			}
+			return
+		}
+		if sent < reminderBatchSize {
+			return
+		}
+	}
+}
+
+func (s *ReminderScheduler) send(ctx context.Context, item dueReminder) error {
+	ctx, cancel := context.WithTimeout(ctx, reminderSendTimeout)
+	defer cancel()
+
+	body := fmt.Sprintf("Todo #%d \"%s\" needs your attention.", item.TodoID, item.Title)
+	if item.DueAt != nil {
+		body = fmt.Sprintf("Todo #%d \"%s\" is due at %s.", item.TodoID, item.Title, item.DueAt.UTC().Format(time.RFC3339))
+	}
+
+	err := s.notifier.Notify(ctx, notify.Notification{
+		Recipient: item.Recipient,
+		Subject:   "Reminder: " + item.Title,
+		Body:      body,
+		Data: map[string]any{
+			"todo_id":     item.TodoID,
+			"reminder_id": item.ID,
+			"due_at":      item.DueAt,
+		},
+	})
+	if err != nil {
+		s.logger.Printf("reminder %d send failed (attempt %d): %v", item.ID, item.Attempts+1, err)
+	}
+	return err
+}
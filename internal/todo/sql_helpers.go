@@ -1,6 +1,9 @@
 package todo
 
-import "database/sql"
+import (
+	"database/sql"
+	"time"
+)
 
 func nullableString(value *string) sql.NullString {
 	// 将可选字符串转换为 SQL 可空类型
@@ -10,10 +13,34 @@ func nullableString(value *string) sql.NullString {
 	return sql.NullString{String: *value, Valid: true}
 }
 
-func nullableBool(value *bool) sql.NullBool {
-	// 将可选布尔转换为 SQL 可空类型
+func nullableStatus(value *Status) sql.NullString {
+	// 将可选状态转换为 SQL 可空类型
 	if value == nil {
-		return sql.NullBool{}
+		return sql.NullString{}
+	}
+	return sql.NullString{String: string(*value), Valid: true}
+}
+
+func nullablePriority(value *Priority) sql.NullString {
+	// 将可选优先级转换为 SQL 可空类型
+	if value == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: string(*value), Valid: true}
+}
+
+func nullableInt64(value *int64) sql.NullInt64 {
+	// 将可选整数转换为 SQL 可空类型
+	if value == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: *value, Valid: true}
+}
+
+func nullableTime(value *time.Time) sql.NullTime {
+	// 将可选时间转换为 SQL 可空类型
+	if value == nil {
+		return sql.NullTime{}
 	}
-	return sql.NullBool{Bool: *value, Valid: true}
+	return sql.NullTime{Time: *value, Valid: true}
 }
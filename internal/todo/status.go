@@ -0,0 +1,123 @@
+package todo
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Status 是 todo 的工作流状态，done 字段由 Status 派生以兼容旧客户端
+type Status string
+
+const (
+	StatusOpen       Status = "open"
+	StatusInProgress Status = "in_progress"
+	StatusBlocked    Status = "blocked"
+	StatusDone       Status = "done"
+	StatusCancelled  Status = "cancelled"
+	StatusSnoozed    Status = "snoozed"
+)
+
+// allowedTransitions 列出每个状态可以直接流转到的目标状态
+var allowedTransitions = map[Status][]Status{
+	StatusOpen:       {StatusInProgress, StatusBlocked, StatusDone, StatusCancelled, StatusSnoozed},
+	StatusInProgress: {StatusOpen, StatusBlocked, StatusDone, StatusCancelled, StatusSnoozed},
+	StatusBlocked:    {StatusOpen, StatusInProgress, StatusCancelled, StatusSnoozed},
+	StatusSnoozed:    {StatusOpen, StatusInProgress, StatusDone, StatusCancelled, StatusSnoozed},
+	StatusDone:       {StatusOpen},
+	StatusCancelled:  {StatusOpen},
+}
+
+func (s Status) Valid() bool {
+	_, ok := allowedTransitions[s]
+	return ok
+}
+
+// Closed 表示 todo 已结束（完成或取消）
+func (s Status) Closed() bool {
+	return s == StatusDone || s == StatusCancelled
+}
+
+func canTransition(from, to Status) bool {
+	// 保持原状态视为合法（例如只修改标题）
+	if from == to && to != StatusSnoozed {
+		return true
+	}
+	for _, next := range allowedTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// statusChange 是校验后的状态变更，nil 表示不修改状态
+type statusChange struct {
+	Status       *Status
+	SnoozedUntil *time.Time
+}
+
+// resolveCreateStatus 把新建视为从 open 出发的一次状态流转，复用同一套校验；未传 done 时不参与判断
+func resolveCreateStatus(input createTodoRequest, now time.Time) (statusChange, error) {
+	return resolveStatusChange(StatusOpen, input.Status, input.Done, input.SnoozedUntil, now)
+}
+
+func resolveStatusChange(current Status, status *string, done *bool, snoozedUntil *time.Time, now time.Time) (statusChange, error) {
+	// 把 status/done/snoozed_until 三种输入统一成目标状态并校验状态机
+	var target *Status
+	// from 是校验状态机时的出发状态，旧客户端的 done=true 可能经 open 中转
+	from := current
+	if status != nil {
+		value := Status(*status)
+		if !value.Valid() {
+			return statusChange{}, fmt.Errorf("invalid status %q", *status)
+		}
+		target = &value
+	}
+
+	if done != nil {
+		// 兼容旧客户端：done=true 等价于完成，done=false 仅把已完成的 todo 重新打开；
+		// 同时给出 status 时两者只需在是否完成上一致
+		if target != nil {
+			if *done != (*target == StatusDone) {
+				return statusChange{}, errors.New("done conflicts with status")
+			}
+		} else {
+			implied := current
+			switch {
+			case *done:
+				implied = StatusDone
+				// 旧客户端不认识 blocked 等状态，不能直接完成时按先重新打开再完成处理
+				if !canTransition(current, StatusDone) && canTransition(current, StatusOpen) {
+					from = StatusOpen
+				}
+			case current == StatusDone:
+				implied = StatusOpen
+			}
+			if implied != current {
+				target = &implied
+			}
+		}
+	}
+
+	if snoozedUntil != nil {
+		if target == nil {
+			snoozed := StatusSnoozed
+			target = &snoozed
+		}
+		if *target != StatusSnoozed {
+			return statusChange{}, errors.New("snoozed_until requires status snoozed")
+		}
+		if !snoozedUntil.After(now) {
+			return statusChange{}, errors.New("snoozed_until must be in the future")
+		}
+	} else if target != nil && *target == StatusSnoozed {
+		return statusChange{}, errors.New("snoozed_until is required to snooze")
+	}
+
+	if target != nil && !canTransition(from, *target) {
+		return statusChange{}, fmt.Errorf("cannot change status from %s to %s", current, *target)
+	}
+
+	return statusChange{Status: target, SnoozedUntil: snoozedUntil}, nil
+}
@@ -0,0 +1,135 @@
+package todo
+
+import (
+	"testing"
+	"time"
+)
+
+func strPtr(value string) *string {
+	return &value
+}
+
+func boolPtr(value bool) *bool {
+	return &value
+}
+
+func TestCanTransition(t *testing.T) {
+	cases := []struct {
+		from, to Status
+		ok       bool
+	}{
+		{StatusOpen, StatusInProgress, true},
+		{StatusOpen, StatusDone, true},
+		{StatusBlocked, StatusDone, false},
+		{StatusDone, StatusOpen, true},
+		{StatusDone, StatusInProgress, false},
+		{StatusCancelled, StatusDone, false},
+		{StatusSnoozed, StatusSnoozed, true},
+		{StatusInProgress, StatusInProgress, true},
+	}
+
+	for _, tc := range cases {
+		if got := canTransition(tc.from, tc.to); got != tc.ok {
+			t.Fatalf("%s -> %s: got %v want %v", tc.from, tc.to, got, tc.ok)
+		}
+	}
+}
+
+func TestResolveStatusChangeLegacyDone(t *testing.T) {
+	now := time.Now()
+
+	change, err := resolveStatusChange(StatusInProgress, nil, boolPtr(true), nil, now)
+	if err != nil || change.Status == nil || *change.Status != StatusDone {
+		t.Fatalf("done=true should complete: %#v %v", change, err)
+	}
+
+	change, err = resolveStatusChange(StatusDone, nil, boolPtr(false), nil, now)
+	if err != nil || change.Status == nil || *change.Status != StatusOpen {
+		t.Fatalf("done=false should reopen: %#v %v", change, err)
+	}
+
+	change, err = resolveStatusChange(StatusBlocked, nil, boolPtr(true), nil, now)
+	if err != nil || change.Status == nil || *change.Status != StatusDone {
+		t.Fatalf("done=true should complete a blocked todo via open: %#v %v", change, err)
+	}
+
+	if _, err := resolveStatusChange(StatusBlocked, strPtr("done"), nil, nil, now); err == nil {
+		t.Fatal("explicit status done on a blocked todo should still be rejected")
+	}
+
+	change, err = resolveStatusChange(StatusBlocked, nil, boolPtr(false), nil, now)
+	if err != nil || change.Status != nil {
+		t.Fatalf("done=false on an unfinished todo should be a no-op: %#v %v", change, err)
+	}
+
+	if _, err := resolveStatusChange(StatusOpen, strPtr("cancelled"), boolPtr(true), nil, now); err == nil {
+		t.Fatal("expected conflict between done and status")
+	}
+}
+
+func TestResolveCreateStatus(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Hour)
+
+	cases := []struct {
+		name  string
+		input createTodoRequest
+		want  Status
+	}{
+		{"default", createTodoRequest{}, ""},
+		{"in_progress", createTodoRequest{Status: strPtr("in_progress")}, StatusInProgress},
+		{"blocked", createTodoRequest{Status: strPtr("blocked")}, StatusBlocked},
+		{"cancelled", createTodoRequest{Status: strPtr("cancelled")}, StatusCancelled},
+		{"done without done flag", createTodoRequest{Status: strPtr("done")}, StatusDone},
+		{"done with done flag", createTodoRequest{Status: strPtr("done"), Done: boolPtr(true)}, StatusDone},
+		{"legacy done flag", createTodoRequest{Done: boolPtr(true)}, StatusDone},
+		{"explicit not done", createTodoRequest{Status: strPtr("in_progress"), Done: boolPtr(false)}, StatusInProgress},
+		{"snoozed", createTodoRequest{SnoozedUntil: &later}, StatusSnoozed},
+	}
+	for _, tc := range cases {
+		change, err := resolveCreateStatus(tc.input, now)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", tc.name, err)
+		}
+		var got Status
+		if change.Status != nil {
+			got = *change.Status
+		}
+		if got != tc.want {
+			t.Fatalf("%s: status = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+
+	for _, input := range []createTodoRequest{
+		{Status: strPtr("blocked"), Done: boolPtr(true)},
+		{Status: strPtr("done"), Done: boolPtr(false)},
+	} {
+		if _, err := resolveCreateStatus(input, now); err == nil {
+			t.Fatalf("expected conflict for status=%s done=%v", *input.Status, *input.Done)
+		}
+	}
+}
+
+func TestResolveStatusChangeSnooze(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Hour)
+	earlier := now.Add(-time.Hour)
+
+	change, err := resolveStatusChange(StatusOpen, nil, nil, &later, now)
+	if err != nil || change.Status == nil || *change.Status != StatusSnoozed {
+		t.Fatalf("snoozed_until should imply snoozed: %#v %v", change, err)
+	}
+
+	if _, err := resolveStatusChange(StatusOpen, strPtr("snoozed"), nil, nil, now); err == nil {
+		t.Fatal("expected error when snoozing without snoozed_until")
+	}
+	if _, err := resolveStatusChange(StatusOpen, nil, nil, &earlier, now); err == nil {
+		t.Fatal("expected error when snoozing into the past")
+	}
+	if _, err := resolveStatusChange(StatusDone, nil, nil, &later, now); err == nil {
+		t.Fatal("expected error when snoozing a done todo")
+	}
+	if _, err := resolveStatusChange(StatusOpen, strPtr("archived"), nil, nil, now); err == nil {
+		t.Fatal("expected error for unknown status")
+	}
+}
@@ -3,8 +3,45 @@ package todo
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrParentNotFound = errors.New("parent todo not found")
+	ErrStatusConflict = errors.New("todo status changed concurrently")
+)
+
+const (
+	uniqueViolationCode     = "23505"
+	foreignKeyViolationCode = "23503"
 )
 
+// todoColumns 是查询 todo 时统一使用的列顺序，需与 scanTodo 保持一致
+const todoColumns = `id, title, done, status, priority, snoozed_until, completed_at, parent_id, list_name, due_at, user_id, archived_at, created_at, updated_at`
+
+func prefixedTodoColumns(alias string) string {
+	// 连表查询时为 todoColumns 加上表别名
+	columns := strings.Split(todoColumns, ", ")
+	for i, column := range columns {
+		columns[i] = alias + "." + column
+	}
+	return strings.Join(columns, ", ")
+}
+
+// queryer 同时由 *sql.DB 与 *sql.Tx 实现，便于在事务内复用同一套 SQL
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
 type Store struct {
 	db *sql.DB
 }
@@ -14,13 +51,22 @@ func NewStore(db *sql.DB) *Store {
 	return &Store{db: db}
 }
 
-func (s *Store) List(ctx context.Context) ([]Todo, error) {
-	// 查询全部 todo
+func (s *Store) List(ctx context.Context, filter listFilter) ([]Todo, error) {
+	// 查询 todo，过滤条件为空时不生效
 	rows, err := s.db.QueryContext(ctx, `
-		SELECT id, title, done, created_at, updated_at
+		SELECT `+todoColumns+`
 		FROM todos
+		WHERE (archived_at IS NOT NULL) = $4
+			AND ($1::text IS NULL OR status = $1)
+			AND ($2::text IS NULL OR list_name = $2)
+			AND (NOT $3 OR NOT EXISTS (
+				SELECT 1
+				FROM todo_dependencies d
+				JOIN todos b ON b.id = d.blocker_id
+				WHERE d.todo_id = todos.id AND b.status NOT IN ('done', 'cancelled')
+			))
 		ORDER BY id DESC
-	`)
+	`, nullableStatus(filter.Status), nullableString(filter.List), filter.Actionable, filter.Archived)
 	if err != nil {
 		return nil, err
 	}
@@ -28,8 +74,8 @@ func (s *Store) List(ctx context.Context) ([]Todo, error) {
 
 	var todos []Todo
 	for rows.Next() {
-		var todo Todo
-		if err := rows.Scan(&todo.ID, &todo.Title, &todo.Done, &todo.CreatedAt, &todo.UpdatedAt); err != nil {
+		todo, err := scanTodo(rows)
+		if err != nil {
 			return nil, err
 		}
 		todos = append(todos, todo)
@@ -42,49 +88,63 @@ func (s *Store) List(ctx context.Context) ([]Todo, error) {
 
 func (s *Store) Get(ctx context.Context, id int64) (Todo, error) {
 	// 按 ID 查询
-	var todo Todo
 	row := s.db.QueryRowContext(ctx, `
-		SELECT id, title, done, created_at, updated_at
+		SELECT `+todoColumns+`
 		FROM todos
 		WHERE id = $1
 	`, id)
-	if err := row.Scan(&todo.ID, &todo.Title, &todo.Done, &todo.CreatedAt, &todo.UpdatedAt); err != nil {
-		return Todo{}, err
-	}
-	return todo, nil
+	return scanTodo(row)
 }
 
 func (s *Store) Create(ctx context.Context, input createTodoRequest) (Todo, error) {
 	// 新建 todo
-	var todo Todo
-	row := s.db.QueryRowContext(ctx, `
-		INSERT INTO todos (title, done)
-		VALUES ($1, $2)
-		RETURNING id, title, done, created_at, updated_at
-	`, input.Title, input.Done)
-	if err := row.Scan(&todo.ID, &todo.Title, &todo.Done, &todo.CreatedAt, &todo.UpdatedAt); err != nil {
-		return Todo{}, err
-	}
-	return todo, nil
+	return createTodo(ctx, s.db, input)
 }
 
-func (s *Store) Update(ctx context.Context, id int64, input updateTodoRequest) (Todo, error) {
-	// 更新 todo（部分字段）
-	var todo Todo
+func (s *Store) Update(ctx context.Context, id int64, expected Status, changes todoChanges) (Todo, error) {
+	// 更新 todo（部分字段）。以 expected 作为乐观锁，状态已被并发修改时返回 ErrStatusConflict；
+	// done、snoozed_until、completed_at 均由新状态派生；ClearDueAt 为 true 时清空截止时间
 	row := s.db.QueryRowContext(ctx, `
 		UPDATE todos
 		SET title = COALESCE($1, title),
-			done = COALESCE($2, done),
+			due_at = CASE WHEN $9 THEN NULL ELSE COALESCE($2, due_at) END,
+			status = COALESCE($3, status),
+			done = COALESCE($3, status) = 'done',
+			snoozed_until = CASE WHEN COALESCE($3, status) = 'snoozed' THEN COALESCE($4, snoozed_until) END,
+			completed_at = CASE WHEN COALESCE($3, status) = 'done' THEN COALESCE(completed_at, NOW()) END,
+			list_name = COALESCE($5, list_name),
+			priority = COALESCE($8, priority),
 			updated_at = NOW()
-		WHERE id = $3
-		RETURNING id, title, done, created_at, updated_at
-	`, nullableString(input.Title), nullableBool(input.Done), id)
-	if err := row.Scan(&todo.ID, &todo.Title, &todo.Done, &todo.CreatedAt, &todo.UpdatedAt); err != nil {
+		WHERE id = $6 AND status = $7
+		RETURNING `+todoColumns+`
+	`, nullableString(changes.Title), nullableTime(changes.DueAt), nullableStatus(changes.Status),
+		nullableTime(changes.SnoozedUntil), nullableString(changes.List), id, string(expected),
+		nullablePriority(changes.Priority), changes.ClearDueAt)
+	todo, err := scanTodo(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Todo{}, ErrStatusConflict
+		}
 		return Todo{}, err
 	}
 	return todo, nil
 }
 
+func (s *Store) Unsnooze(ctx context.Context) (int64, error) {
+	// 把 snooze 时间已过的 todo 恢复为 open
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE todos
+		SET status = 'open',
+			snoozed_until = NULL,
+			updated_at = NOW()
+		WHERE status = 'snoozed' AND snoozed_until <= NOW()
+	`)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
 func (s *Store) Delete(ctx context.Context, id int64) (bool, error) {
 	// 删除 todo
 	res, err := s.db.ExecContext(ctx, `
@@ -100,3 +160,59 @@ func (s *Store) Delete(ctx context.Context, id int64) (bool, error) {
 	}
 	return affected > 0, nil
 }
+
+func createTodo(ctx context.Context, q queryer, input createTodoRequest) (Todo, error) {
+	// 插入单条 todo，可运行在事务或普通连接上
+	row := q.QueryRowContext(ctx, `
+		INSERT INTO todos (title, status, done, snoozed_until, completed_at, parent_id, list_name, due_at, user_id, priority)
+		VALUES ($1, $2::text, $2::text = 'done', $3, CASE WHEN $2::text = 'done' THEN NOW() END, $4, $5, $6, $7, COALESCE($8, 'medium'))
+		RETURNING `+todoColumns+`
+	`, input.Title, string(createStatus(input)), nullableTime(input.SnoozedUntil),
+		nullableInt64(input.ParentID), nullableString(input.List), nullableTime(input.DueAt), nullableInt64(input.UserID),
+		nullableString(input.Priority))
+	todo, err := scanTodo(row)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return Todo{}, ErrParentNotFound
+		}
+		return Todo{}, err
+	}
+	return todo, nil
+}
+
+func createStatus(input createTodoRequest) Status {
+	// 新建时的状态：显式 status 优先，其次兼容 done 字段
+	if input.Status != nil {
+		return Status(*input.Status)
+	}
+	if input.Done != nil && *input.Done {
+		return StatusDone
+	}
+	return StatusOpen
+}
+
+func scanTodo(row rowScanner) (Todo, error) {
+	// 按 todoColumns 的顺序扫描一行
+	var todo Todo
+	if err := row.Scan(&todo.ID, &todo.Title, &todo.Done, &todo.Status, &todo.Priority, &todo.SnoozedUntil, &todo.CompletedAt,
+		&todo.ParentID, &todo.List, &todo.DueAt, &todo.UserID, &todo.ArchivedAt, &todo.CreatedAt, &todo.UpdatedAt); err != nil {
+		return Todo{}, err
+	}
+	return todo, nil
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == foreignKeyViolationCode
+	}
+	return false
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == uniqueViolationCode
+	}
+	return false
+}
@@ -0,0 +1,186 @@
+package todo
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+func (h *Handler) handleListTemplates(w http.ResponseWriter, r *http.Request) {
+	// 模板列表
+	items, err := h.store.ListTemplates(r.Context())
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to load templates")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, map[string]any{"templates": items})
+}
+
+func (h *Handler) handleGetTemplate(w http.ResponseWriter, r *http.Request) {
+	// 单个模板
+	id, err := readIDParam(r)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	template, err := h.store.GetTemplate(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.writeError(w, http.StatusNotFound, "template not found")
+			return
+		}
+		h.writeError(w, http.StatusInternalServerError, "failed to load template")
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, template)
+}
+
+func (h *Handler) handleCreateTemplate(w http.ResponseWriter, r *http.Request) {
+	// 创建模板
+	var input templateRequest
+	if err := h.decodeJSON(w, r, &input); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := normalizeTemplateRequest(&input); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	template, err := h.store.CreateTemplate(r.Context(), input)
+	if err != nil {
+		if errors.Is(err, ErrTemplateNameExists) {
+			h.writeError(w, http.StatusConflict, "template name already exists")
+			return
+		}
+		h.writeError(w, http.StatusInternalServerError, "failed to create template")
+		return
+	}
+
+	h.writeJSON(w, http.StatusCreated, template)
+}
+
+func (h *Handler) handleUpdateTemplate(w http.ResponseWriter, r *http.Request) {
+	// 整体替换模板
+	id, err := readIDParam(r)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var input templateRequest
+	if err := h.decodeJSON(w, r, &input); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := normalizeTemplateRequest(&input); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	template, err := h.store.UpdateTemplate(r.Context(), id, input)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.writeError(w, http.StatusNotFound, "template not found")
+			return
+		}
+		if errors.Is(err, ErrTemplateNameExists) {
+			h.writeError(w, http.StatusConflict, "template name already exists")
+			return
+		}
+		h.writeError(w, http.StatusInternalServerError, "failed to update template")
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, template)
+}
+
+func (h *Handler) handleDeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	// 删除模板
+	id, err := readIDParam(r)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	deleted, err := h.store.DeleteTemplate(r.Context(), id)
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to delete template")
+		return
+	}
+	if !deleted {
+		h.writeError(w, http.StatusNotFound, "template not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) handleInstantiateTemplate(w http.ResponseWriter, r *http.Request) {
+	// 按模板批量生成 todo：替换占位符、计算相对截止时间，并在一个事务中写入
+	id, err := readIDParam(r)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var input instantiateTemplateRequest
+	if err := h.decodeJSON(w, r, &input); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	template, err := h.store.GetTemplate(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.writeError(w, http.StatusNotFound, "template not found")
+			return
+		}
+		h.writeError(w, http.StatusInternalServerError, "failed to load template")
+		return
+	}
+
+	startAt := time.Now()
+	if input.StartAt != nil {
+		startAt = *input.StartAt
+	}
+
+	plan, err := renderTemplate(template.Items, input.Variables, startAt)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// 清单名同样支持占位符，例如 "release {{release}}"
+	base := createTodoRequest{UserID: ownerFromRequest(r)}
+	if input.List != nil {
+		rendered, err := renderPlaceholders(*input.List, input.Variables)
+		if err != nil {
+			h.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		base.List = trimmedOptional(&rendered)
+	}
+
+	todos, err := h.store.CreateFromPlan(r.Context(), plan, base)
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to instantiate template")
+		return
+	}
+
+	h.writeJSON(w, http.StatusCreated, map[string]any{"todos": todos})
+}
+
+func normalizeTemplateRequest(input *templateRequest) error {
+	// 去除首尾空白并校验模板结构
+	input.Name = strings.TrimSpace(input.Name)
+	input.Description = strings.TrimSpace(input.Description)
+	if input.Name == "" {
+		return errors.New("name is required")
+	}
+	return validateTemplateItems(input.Items)
+}
@@ -0,0 +1,33 @@
+package todo
+
+import "time"
+
+// Template 描述一份可重复实例化的清单，Items 为 todo 定义树
+type Template struct {
+	ID          int64          `json:"id"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Items       []TemplateItem `json:"items"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+// TemplateItem 是模板中的单个 todo 定义
+// Title 可包含 {{name}} 形式的占位符；DueOffset 为相对实例化时间的截止偏移，如 "3d"、"36h"
+type TemplateItem struct {
+	Title     string         `json:"title"`
+	DueOffset string         `json:"due_offset,omitempty"`
+	Children  []TemplateItem `json:"children,omitempty"`
+}
+
+type templateRequest struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Items       []TemplateItem `json:"items"`
+}
+
+type instantiateTemplateRequest struct {
+	Variables map[string]string `json:"variables"`
+	StartAt   *time.Time        `json:"start_at"`
+	List      *string           `json:"list"`
+}
@@ -0,0 +1,121 @@
+package todo
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	maxTemplateDepth = 5
+	maxTemplateItems = 200
+)
+
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_]+)\s*\}\}`)
+
+// plannedTodo 是模板渲染后的待创建节点，占位符与截止时间均已解析
+type plannedTodo struct {
+	Title    string
+	DueAt    *time.Time
+	Children []plannedTodo
+}
+
+func validateTemplateItems(items []TemplateItem) error {
+	// 校验模板结构：标题非空、偏移合法、深度和数量受限
+	if len(items) == 0 {
+		return errors.New("items are required")
+	}
+	count := 0
+	var walk func(items []TemplateItem, depth int) error
+	walk = func(items []TemplateItem, depth int) error {
+		if depth > maxTemplateDepth {
+			return fmt.Errorf("items nested deeper than %d levels", maxTemplateDepth)
+		}
+		for _, item := range items {
+			count++
+			if count > maxTemplateItems {
+				return fmt.Errorf("template cannot contain more than %d items", maxTemplateItems)
+			}
+			if strings.TrimSpace(item.Title) == "" {
+				return errors.New("item title is required")
+			}
+			if item.DueOffset != "" {
+				if _, err := parseOffset(item.DueOffset); err != nil {
+					return fmt.Errorf("invalid due_offset %q", item.DueOffset)
+				}
+			}
+			if err := walk(item.Children, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walk(items, 1)
+}
+
+func renderTemplate(items []TemplateItem, vars map[string]string, startAt time.Time) ([]plannedTodo, error) {
+	// 替换占位符并把相对偏移换算成绝对截止时间
+	planned := make([]plannedTodo, 0, len(items))
+	for _, item := range items {
+		title, err := renderPlaceholders(item.Title, vars)
+		if err != nil {
+			return nil, err
+		}
+
+		node := plannedTodo{Title: strings.TrimSpace(title)}
+		if node.Title == "" {
+			return nil, errors.New("rendered title is empty")
+		}
+		if item.DueOffset != "" {
+			offset, err := parseOffset(item.DueOffset)
+			if err != nil {
+				return nil, fmt.Errorf("invalid due_offset %q", item.DueOffset)
+			}
+			dueAt := startAt.Add(offset)
+			node.DueAt = &dueAt
+		}
+
+		node.Children, err = renderTemplate(item.Children, vars, startAt)
+		if err != nil {
+			return nil, err
+		}
+		planned = append(planned, node)
+	}
+	return planned, nil
+}
+
+func renderPlaceholders(text string, vars map[string]string) (string, error) {
+	// 所有占位符都必须提供取值，避免生成带 {{...}} 的标题
+	var missing string
+	rendered := placeholderPattern.ReplaceAllStringFunc(text, func(match string) string {
+		name := placeholderPattern.FindStringSubmatch(match)[1]
+		value, ok := vars[name]
+		if !ok {
+			if missing == "" {
+				missing = name
+			}
+			return match
+		}
+		return value
+	})
+	if missing != "" {
+		return "", fmt.Errorf("missing variable: %s", missing)
+	}
+	return rendered, nil
+}
+
+func parseOffset(value string) (time.Duration, error) {
+	// 在 time.ParseDuration 基础上支持以天为单位的 "3d"
+	value = strings.TrimSpace(value)
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(value)
+}
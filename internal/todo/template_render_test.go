@@ -0,0 +1,76 @@
+package todo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	startAt := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	items := []TemplateItem{
+		{
+			Title:     "Release {{release}}",
+			DueOffset: "7d",
+			Children: []TemplateItem{
+				{Title: "Tag {{ release }} in git", DueOffset: "36h"},
+				{Title: "Write changelog"},
+			},
+		},
+	}
+
+	plan, err := renderTemplate(items, map[string]string{"release": "v1.2"}, startAt)
+	if err != nil {
+		t.Fatalf("render template: %v", err)
+	}
+	if len(plan) != 1 || len(plan[0].Children) != 2 {
+		t.Fatalf("unexpected plan shape: %#v", plan)
+	}
+
+	root := plan[0]
+	if root.Title != "Release v1.2" {
+		t.Fatalf("unexpected root title: %q", root.Title)
+	}
+	if root.DueAt == nil || !root.DueAt.Equal(startAt.Add(7*24*time.Hour)) {
+		t.Fatalf("unexpected root due_at: %v", root.DueAt)
+	}
+
+	tag := root.Children[0]
+	if tag.Title != "Tag v1.2 in git" {
+		t.Fatalf("unexpected child title: %q", tag.Title)
+	}
+	if tag.DueAt == nil || !tag.DueAt.Equal(startAt.Add(36*time.Hour)) {
+		t.Fatalf("unexpected child due_at: %v", tag.DueAt)
+	}
+	if root.Children[1].DueAt != nil {
+		t.Fatalf("expected no due_at without offset, got %v", root.Children[1].DueAt)
+	}
+}
+
+func TestRenderTemplateMissingVariable(t *testing.T) {
+	items := []TemplateItem{{Title: "Onboard {{name}} to {{team}}"}}
+
+	_, err := renderTemplate(items, map[string]string{"name": "alice"}, time.Now())
+	if err == nil || err.Error() != "missing variable: team" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestValidateTemplateItems(t *testing.T) {
+	cases := []struct {
+		name  string
+		items []TemplateItem
+		ok    bool
+	}{
+		{name: "valid", items: []TemplateItem{{Title: "a", DueOffset: "2d"}}, ok: true},
+		{name: "empty", items: nil, ok: false},
+		{name: "blank title", items: []TemplateItem{{Title: "  "}}, ok: false},
+		{name: "bad offset", items: []TemplateItem{{Title: "a", DueOffset: "soon"}}, ok: false},
+	}
+
+	for _, tc := range cases {
+		err := validateTemplateItems(tc.items)
+		if (err == nil) != tc.ok {
+			t.Fatalf("%s: unexpected result: %v", tc.name, err)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package todo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+var ErrTemplateNameExists = errors.New("template name already exists")
+
+const templateColumns = `id, name, description, items, created_at, updated_at`
+
+func (s *Store) ListTemplates(ctx context.Context) ([]Template, error) {
+	// 查询全部模板
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+templateColumns+`
+		FROM todo_templates
+		ORDER BY id DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []Template
+	for rows.Next() {
+		template, err := scanTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, template)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+func (s *Store) GetTemplate(ctx context.Context, id int64) (Template, error) {
+	// 按 ID 查询模板
+	row := s.db.QueryRowContext(ctx, `
+		SELECT `+templateColumns+`
+		FROM todo_templates
+		WHERE id = $1
+	`, id)
+	return scanTemplate(row)
+}
+
+func (s *Store) CreateTemplate(ctx context.Context, input templateRequest) (Template, error) {
+	// 新建模板，items 以 JSONB 存储
+	items, err := json.Marshal(input.Items)
+	if err != nil {
+		return Template{}, err
+	}
+	row := s.db.QueryRowContext(ctx, `
+		INSERT INTO todo_templates (name, description, items)
+		VALUES ($1, $2, $3)
+		RETURNING `+templateColumns+`
+	`, input.Name, input.Description, string(items))
+	template, err := scanTemplate(row)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return Template{}, ErrTemplateNameExists
+		}
+		return Template{}, err
+	}
+	return template, nil
+}
+
+func (s *Store) UpdateTemplate(ctx context.Context, id int64, input templateRequest) (Template, error) {
+	// 整体替换模板内容
+	items, err := json.Marshal(input.Items)
+	if err != nil {
+		return Template{}, err
+	}
+	row := s.db.QueryRowContext(ctx, `
+		UPDATE todo_templates
+		SET name = $1,
+			description = $2,
+			items = $3,
+			updated_at = NOW()
+		WHERE id = $4
+		RETURNING `+templateColumns+`
+	`, input.Name, input.Description, string(items), id)
+	template, err := scanTemplate(row)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return Template{}, ErrTemplateNameExists
+		}
+		return Template{}, err
+	}
+	return template, nil
+}
+
+func (s *Store) DeleteTemplate(ctx context.Context, id int64) (bool, error) {
+	// 删除模板，已生成的 todo 不受影响
+	res, err := s.db.ExecContext(ctx, `
+		DELETE FROM todo_templates
+		WHERE id = $1
+	`, id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+func (s *Store) CreateFromPlan(ctx context.Context, plan []plannedTodo, base createTodoRequest) ([]Todo, error) {
+	// 在同一事务中按树结构创建 todo，任一失败则全部回滚；base 中的清单与归属用户应用到所有 todo
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var created []Todo
+	var insert func(nodes []plannedTodo, parentID *int64) error
+	insert = func(nodes []plannedTodo, parentID *int64) error {
+		for _, node := range nodes {
+			input := base
+			input.Title = node.Title
+			input.ParentID = parentID
+			input.DueAt = node.DueAt
+			todo, err := createTodo(ctx, tx, input)
+			if err != nil {
+				return err
+			}
+			created = append(created, todo)
+			if err := insert(node.Children, &todo.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := insert(plan, nil); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func scanTemplate(row rowScanner) (Template, error) {
+	// 扫描模板并反序列化 items
+	var template Template
+	var items []byte
+	if err := row.Scan(&template.ID, &template.Name, &template.Description, &items, &template.CreatedAt, &template.UpdatedAt); err != nil {
+		return Template{}, err
+	}
+	if err := json.Unmarshal(items, &template.Items); err != nil {
+		return Template{}, err
+	}
+	return template, nil
+}
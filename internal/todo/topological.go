@@ -0,0 +1,68 @@
+package todo
+
+import "container/heap"
+
+// topologicalOrder 用 Kahn 算法排序：前置任务排在被阻塞的 todo 之前，
+// 同一层级按 ID 升序输出以保证结果稳定；只考虑两端都在 todos 中的依赖边
+func topologicalOrder(todos []Todo, edges []dependency) ([]Todo, error) {
+	byID := make(map[int64]Todo, len(todos))
+	for _, todo := range todos {
+		byID[todo.ID] = todo
+	}
+
+	indegree := make(map[int64]int, len(todos))
+	dependents := make(map[int64][]int64)
+	for _, edge := range edges {
+		if _, ok := byID[edge.TodoID]; !ok {
+			continue
+		}
+		if _, ok := byID[edge.BlockerID]; !ok {
+			continue
+		}
+		indegree[edge.TodoID]++
+		dependents[edge.BlockerID] = append(dependents[edge.BlockerID], edge.TodoID)
+	}
+
+	ready := &idHeap{}
+	for id := range byID {
+		if indegree[id] == 0 {
+			heap.Push(ready, id)
+		}
+	}
+
+	ordered := make([]Todo, 0, len(todos))
+	for ready.Len() > 0 {
+		id := heap.Pop(ready).(int64)
+		ordered = append(ordered, byID[id])
+		for _, next := range dependents[id] {
+			indegree[next]--
+			if indegree[next] == 0 {
+				heap.Push(ready, next)
+			}
+		}
+	}
+
+	if len(ordered) != len(byID) {
+		return nil, ErrDependencyCycle
+	}
+	return ordered, nil
+}
+
+// idHeap 是按 ID 升序弹出的最小堆
+type idHeap []int64
+
+func (h idHeap) Len() int           { return len(h) }
+func (h idHeap) Less(i, j int) bool { return h[i] < h[j] }
+func (h idHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *idHeap) Push(x any) {
+	*h = append(*h, x.(int64))
+}
+
+func (h *idHeap) Pop() any {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
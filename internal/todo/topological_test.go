@@ -0,0 +1,45 @@
+package todo
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func todoIDs(todos []Todo) []int64 {
+	ids := make([]int64, 0, len(todos))
+	for _, todo := range todos {
+		ids = append(ids, todo.ID)
+	}
+	return ids
+}
+
+func TestTopologicalOrder(t *testing.T) {
+	todos := []Todo{{ID: 5}, {ID: 4}, {ID: 3}, {ID: 2}, {ID: 1}}
+	edges := []dependency{
+		{TodoID: 1, BlockerID: 4},
+		{TodoID: 2, BlockerID: 1},
+		{TodoID: 2, BlockerID: 3},
+		// 清单外的前置任务不参与排序
+		{TodoID: 5, BlockerID: 99},
+	}
+
+	ordered, err := topologicalOrder(todos, edges)
+	if err != nil {
+		t.Fatalf("topological order: %v", err)
+	}
+
+	want := []int64{3, 4, 1, 2, 5}
+	if got := todoIDs(ordered); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected order: got %v want %v", got, want)
+	}
+}
+
+func TestTopologicalOrderCycle(t *testing.T) {
+	todos := []Todo{{ID: 1}, {ID: 2}}
+	edges := []dependency{{TodoID: 1, BlockerID: 2}, {TodoID: 2, BlockerID: 1}}
+
+	if _, err := topologicalOrder(todos, edges); !errors.Is(err, ErrDependencyCycle) {
+		t.Fatalf("expected cycle error, got %v", err)
+	}
+}
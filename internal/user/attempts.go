@@ -0,0 +1,144 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"go_test/internal/auth"
+)
+
+func (s *Store) RecordAttempt(ctx context.Context, kind, email, ip, outcome string) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO auth_attempts (kind, email, ip, outcome)
+		VALUES ($1, $2, $3, $4)
+	`, kind, email, nullIfEmpty(ip), outcome)
+	return err
+}
+
+func (s *Store) AttemptStats(ctx context.Context, kind, outcome, email, ip string, since time.Time) (attemptStats, attemptStats, error) {
+	var byEmail, byIP attemptStats
+	var emailFirst, emailLast, ipFirst, ipLast sql.NullTime
+	row := s.db.QueryRowContext(ctx, `
+		WITH reset AS (
+			SELECT COALESCE(MAX(created_at), '-infinity'::timestamptz) AS at
+			FROM auth_attempts
+			WHERE kind = $1 AND email = $3 AND outcome IN ('success', 'unlock')
+		)
+		SELECT COUNT(*) FILTER (WHERE a.email = $3 AND a.created_at > reset.at),
+			MIN(a.created_at) FILTER (WHERE a.email = $3 AND a.created_at > reset.at),
+			MAX(a.created_at) FILTER (WHERE a.email = $3 AND a.created_at > reset.at),
+			COUNT(*) FILTER (WHERE a.ip = $4),
+			MIN(a.created_at) FILTER (WHERE a.ip = $4),
+			MAX(a.created_at) FILTER (WHERE a.ip = $4)
+		FROM reset
+		LEFT JOIN auth_attempts a
+			ON a.kind = $1 AND a.outcome = $2 AND a.created_at > $5 AND (a.email = $3 OR a.ip = $4)
+	`, kind, outcome, email, nullIfEmpty(ip), since)
+	if err := row.Scan(&byEmail.Count, &emailFirst, &emailLast, &byIP.Count, &ipFirst, &ipLast); err != nil {
+		return attemptStats{}, attemptStats{}, err
+	}
+	byEmail.First, byEmail.Last = emailFirst.Time, emailLast.Time
+	byIP.First, byIP.Last = ipFirst.Time, ipLast.Time
+	return byEmail, byIP, nil
+}
+
+func (s *Store) CreateAccountUnlock(ctx context.Context, userID int64, token string, expiresAt time.Time) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO account_unlocks (user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+	`, userID, auth.HashToken(token), expiresAt)
+	return err
+}
+
+func (s *Store) ConsumeAccountUnlock(ctx context.Context, token, ip string) (User, error) {
+	var user User
+	row := s.db.QueryRowContext(ctx, `
+		WITH unlock AS (
+			UPDATE account_unlocks
+			SET consumed_at = NOW()
+			WHERE token_hash = $1 AND expires_at > NOW() AND consumed_at IS NULL
+			RETURNING user_id
+		),
+		account AS (
+			SELECT `+prefixedUserColumns("u")+`
+			FROM users u
+			JOIN unlock ON unlock.user_id = u.id
+		),
+		recorded AS (
+			INSERT INTO auth_attempts (kind, email, ip, outcome)
+			SELECT $2, email, $3, $4
+			FROM account
+		)
+		SELECT `+userColumns+`
+		FROM account
+	`, auth.HashToken(token), attemptLogin, nullIfEmpty(ip), outcomeUnlock)
+	if err := scanUser(row, &user); err != nil {
+		return User{}, err
+	}
+	return user, nil
+}
+
+func (s *Store) PruneAuthAttempts(ctx context.Context, before time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `
+		DELETE FROM auth_attempts
+		WHERE created_at < $1
+	`, before)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := s.db.ExecContext(ctx, `
+		DELETE FROM account_unlocks
+		WHERE expires_at < $1
+	`, before); err != nil {
+		return 0, err
+	}
+	if _, err := s.db.ExecContext(ctx, `
+		DELETE FROM login_challenges
+		WHERE expires_at < $1
+	`, before); err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+type AttemptPruner struct {
+	store     *Store
+	logger    *log.Logger
+	interval  time.Duration
+	retention time.Duration
+}
+
+func NewAttemptPruner(store *Store, logger *log.Logger, interval, retention time.Duration) *AttemptPruner {
+	return &AttemptPruner{
+		store:     store,
+		logger:    logger,
+		interval:  interval,
+		retention: retention,
+	}
+}
+
+func (p *AttemptPruner) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		count, err := p.store.PruneAuthAttempts(ctx, time.Now().Add(-p.retention))
+		if err != nil {
+			if ctx.Err() == nil {
+				p.logger.Printf("prune auth attempts error: %v", err)
+			}
+			continue
+		}
+		if count > 0 {
+			p.logger.Printf("pruned %d auth attempt(s)", count)
+		}
+	}
+}
@@ -1,17 +1,28 @@
 package user
 
 import (
+	"context"
 	"crypto/rand"
 	"database/sql"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"log"
+	"math"
+	"net"
 	"net/http"
+	netmail "net/mail"
+	"strconv"
 	"strings"
 	"time"
 
+	"go_test/internal/jwt"
+	"go_test/internal/mail"
+	"go_test/internal/metrics"
+	"go_test/internal/pwpolicy"
+
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/go-chi/chi/v5"
@@ -20,43 +31,202 @@ import (
 
 var errUnauthorized = errors.New("unauthorized")
 
+var jwtOptionalClaims = []string{"email", "name"}
+
+const (
+	maxUserAgentLength = 512
+	maxEmailLength     = 254
+
+	maxVerificationEmailsPerDay = 5
+
+	loginChallengeTTL    = 5 * time.Minute
+	maxChallengeAttempts = 5
+)
+
 const (
-	minPasswordLength = 8
+	VerificationOff      = "off"
+	VerificationLimited  = "limited"
+	VerificationRequired = "required"
 )
 
 type Handler struct {
 	store      *Store
 	logger     *log.Logger
-	sessionTTL time.Duration
+	metrics    *metrics.Registry
+	accessTTL  time.Duration
+	refreshTTL time.Duration
 	resetTTL   time.Duration
+	signer     *jwt.Signer
+	jwtClaims  map[string]bool
+
+	mailer         mail.Mailer
+	devMode        bool
+	verification   string
+	verifyTTL      time.Duration
+	resendInterval time.Duration
+
+	loginPolicy    LoginPolicy
+	forgotPolicy   ForgotPolicy
+	passwordPolicy pwpolicy.Policy
+
+	totpIssuer string
 }
 
 func NewHandler(store *Store, logger *log.Logger) *Handler {
+	registry := metrics.NewRegistry(logger)
+	if store != nil {
+		registry.Register(metrics.DBStatsCollector(store.db))
+		registry.Register(store.collectMetrics)
+	}
 	return &Handler{
 		store:      store,
 		logger:     logger,
-		sessionTTL: 24 * time.Hour,
+		metrics:    registry,
+		accessTTL:  15 * time.Minute,
+		refreshTTL: 30 * 24 * time.Hour,
 		resetTTL:   30 * time.Minute,
+
+		verification:   VerificationOff,
+		verifyTTL:      24 * time.Hour,
+		resendInterval: time.Minute,
+
+		loginPolicy: LoginPolicy{
+			MaxFailures:   5,
+			Lockout:       15 * time.Minute,
+			Window:        time.Hour,
+			IPMaxFailures: 50,
+			MaxDelay:      30 * time.Second,
+			UnlockTTL:     time.Hour,
+		},
+		forgotPolicy: ForgotPolicy{
+			MaxPerEmail: 3,
+			MaxPerIP:    20,
+			Window:      time.Hour,
+		},
+		passwordPolicy: pwpolicy.Policy{
+			MinLength:        8,
+			MaxLength:        64,
+			DisallowPersonal: true,
+		},
+
+		totpIssuer: "go-api",
+	}
+}
+
+func (h *Handler) WithLoginPolicy(policy LoginPolicy) *Handler {
+	h.loginPolicy = policy
+	return h
+}
+
+func (h *Handler) WithForgotPolicy(policy ForgotPolicy) *Handler {
+	h.forgotPolicy = policy
+	return h
+}
+
+func (h *Handler) WithPasswordPolicy(policy pwpolicy.Policy) *Handler {
+	h.passwordPolicy = policy
+	return h
+}
+
+func (h *Handler) WithTOTPIssuer(issuer string) *Handler {
+	h.totpIssuer = issuer
+	return h
+}
+
+func (h *Handler) WithTokenTTL(access, refresh time.Duration) *Handler {
+	h.accessTTL = access
+	h.refreshTTL = refresh
+	return h
+}
+
+func (h *Handler) WithJWT(signer *jwt.Signer, claims []string) *Handler {
+	h.signer = signer
+	h.jwtClaims = make(map[string]bool, len(claims))
+	for _, claim := range claims {
+		h.jwtClaims[claim] = true
+	}
+	return h
+}
+
+func (h *Handler) WithMailer(mailer mail.Mailer) *Handler {
+	h.mailer = mailer
+	return h
+}
+
+func (h *Handler) WithDevMode() *Handler {
+	h.devMode = true
+	return h
+}
+
+func (h *Handler) WithEmailVerification(mode string, ttl, resendInterval time.Duration) *Handler {
+	h.verification = mode
+	h.verifyTTL = ttl
+	h.resendInterval = resendInterval
+	return h
+}
+
+func ParseVerificationMode(value string) (string, error) {
+	switch value {
+	case VerificationOff, VerificationLimited, VerificationRequired:
+		return value, nil
+	default:
+		return "", fmt.Errorf("unknown email verification mode %q (allowed: off, limited, required)", value)
 	}
 }
 
+func ParseJWTClaims(value string) ([]string, error) {
+	claims := []string{}
+	for _, part := range strings.Split(value, ",") {
+		claim := strings.TrimSpace(part)
+		if claim == "" {
+			continue
+		}
+		known := false
+		for _, optional := range jwtOptionalClaims {
+			known = known || claim == optional
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown jwt claim %q (allowed: %s)", claim, strings.Join(jwtOptionalClaims, ", "))
+		}
+		claims = append(claims, claim)
+	}
+	return claims, nil
+}
+
 func (h *Handler) Routes() http.Handler {
 	r := chi.NewRouter()
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
+	r.Use(h.metrics.Middleware)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.Timeout(30 * time.Second))
 
 	r.Get("/health", h.handleHealth)
+	r.Method(http.MethodGet, "/metrics", h.metrics)
+	r.Get("/.well-known/jwks.json", h.handleJWKS)
 
 	r.Route("/users", func(r chi.Router) {
 		r.Post("/register", h.handleRegister)
 		r.Post("/login", h.handleLogin)
+		r.Post("/login/2fa", h.handleTwoFactorLogin)
 		r.Post("/password/forgot", h.handleForgotPassword)
 		r.Post("/password/reset", h.handleResetPassword)
+		r.Post("/unlock", h.handleUnlockAccount)
+		r.Post("/email/verify", h.handleVerifyEmail)
+		r.Post("/email/resend", h.handleResendVerification)
+		r.Post("/token/refresh", h.handleRefreshToken)
+		r.Post("/logout", h.handleLogout)
 		r.Get("/me", h.handleGetProfile)
 		r.Put("/me", h.handleUpdateProfile)
+		r.Put("/me/password", h.handleChangePassword)
+		r.Get("/me/sessions", h.handleListSessions)
+		r.Delete("/me/sessions", h.handleRevokeAllSessions)
+		r.Delete("/me/sessions/{id}", h.handleRevokeSession)
+		r.Get("/me/2fa", h.handleTwoFactorStatus)
+		r.Post("/me/2fa/enroll", h.handleTwoFactorEnroll)
+		r.Post("/me/2fa/confirm", h.handleTwoFactorConfirm)
+		r.Post("/me/2fa/disable", h.handleTwoFactorDisable)
 	})
 
 	return r
@@ -66,6 +236,15 @@ func (h *Handler) handleHealth(w http.ResponseWriter, r *http.Request) {
 	h.writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
+func (h *Handler) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	keys := jwt.JWKS{Keys: []jwt.JWK{}}
+	if h.signer != nil {
+		keys = h.signer.JWKS()
+	}
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	h.writeJSON(w, http.StatusOK, keys)
+}
+
 func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
 	var input RegisterRequest
 	if err := h.decodeJSON(w, r, &input); err != nil {
@@ -76,7 +255,7 @@ func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
 	email := normalizeEmail(input.Email)
 	name := strings.TrimSpace(input.Name)
 
-	if !isValidEmail(email) {
+	if !isDeliverableEmail(email) {
 		h.writeError(w, http.StatusBadRequest, "invalid email")
 		return
 	}
@@ -84,8 +263,7 @@ func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
 		h.writeError(w, http.StatusBadRequest, "name is required")
 		return
 	}
-	if len(input.Password) < minPasswordLength {
-		h.writeError(w, http.StatusBadRequest, "password too short")
+	if !h.checkPasswordPolicy(w, input.Password, email, name) {
 		return
 	}
 
@@ -105,6 +283,10 @@ func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := h.sendVerification(r.Context(), user, requestLanguage(r)); err != nil {
+		h.logger.Printf("send verification email to user %d: %v", user.ID, err)
+	}
+
 	h.writeJSON(w, http.StatusCreated, user)
 }
 
@@ -121,9 +303,22 @@ func (h *Handler) handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ip := sessionClient(r).IP
+	now := time.Now()
+	failures, ipFailures, err := h.store.AttemptStats(r.Context(), attemptLogin, outcomeFailure, email, ip, h.loginPolicy.since(now))
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to check login attempts")
+		return
+	}
+	if wait, reason := h.loginPolicy.retryAfter(failures, ipFailures, now); wait > 0 {
+		h.writeRetryAfter(w, wait, reason)
+		return
+	}
+
 	user, err := h.store.GetUserByEmail(r.Context(), email)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
+			h.recordLoginFailure(r, nil, email, ip, failures.Count+1)
 			h.writeError(w, http.StatusUnauthorized, "invalid credentials")
 			return
 		}
@@ -132,23 +327,166 @@ func (h *Handler) handleLogin(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := verifyPassword(user.PasswordHash, input.Password); err != nil {
+		h.recordLoginFailure(r, &user, email, ip, failures.Count+1)
 		h.writeError(w, http.StatusUnauthorized, "invalid credentials")
 		return
 	}
 
-	token, err := generateToken(32)
+	twoFactor, err := h.store.GetTwoFactor(r.Context(), user.ID)
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to load user")
+		return
+	}
+	if !twoFactor.enabled() {
+		if err := h.store.RecordAttempt(r.Context(), attemptLogin, email, ip, outcomeSuccess); err != nil {
+			h.logger.Printf("record login attempt: %v", err)
+		}
+	}
+	if h.verification == VerificationRequired && user.EmailVerifiedAt == nil {
+		h.writeError(w, http.StatusForbidden, "email not verified")
+		return
+	}
+
+	if twoFactor.enabled() {
+		challenge, err := generateToken(32)
+		if err != nil {
+			h.writeError(w, http.StatusInternalServerError, "failed to create login challenge")
+			return
+		}
+		expiresAt := time.Now().Add(loginChallengeTTL)
+		if err := h.store.CreateLoginChallenge(r.Context(), user.ID, challenge, expiresAt); err != nil {
+			h.writeError(w, http.StatusInternalServerError, "failed to create login challenge")
+			return
+		}
+		h.writeJSON(w, http.StatusOK, TwoFactorChallenge{TwoFactorRequired: true, Challenge: challenge, ExpiresAt: expiresAt})
+		return
+	}
+
+	h.startSession(w, r, user)
+}
+
+func (h *Handler) handleTwoFactorLogin(w http.ResponseWriter, r *http.Request) {
+	var input TwoFactorLoginRequest
+	if err := h.decodeJSON(w, r, &input); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	challenge := strings.TrimSpace(input.Challenge)
+	if challenge == "" {
+		h.writeError(w, http.StatusBadRequest, "challenge is required")
+		return
+	}
+	code := strings.TrimSpace(input.Code)
+	recoveryCode := strings.TrimSpace(input.RecoveryCode)
+	if (code == "") == (recoveryCode == "") {
+		h.writeError(w, http.StatusBadRequest, "provide code or recovery_code")
+		return
+	}
+
+	user, err := h.store.GetLoginChallenge(r.Context(), challenge, maxChallengeAttempts)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.writeError(w, http.StatusUnauthorized, "invalid or expired challenge")
+			return
+		}
+		h.writeError(w, http.StatusInternalServerError, "failed to load login challenge")
+		return
+	}
+
+	ip := sessionClient(r).IP
+	now := time.Now()
+	failures, ipFailures, err := h.store.AttemptStats(r.Context(), attemptLogin, outcomeFailure, user.Email, ip, h.loginPolicy.since(now))
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to check login attempts")
+		return
+	}
+	if wait, reason := h.loginPolicy.retryAfter(failures, ipFailures, now); wait > 0 {
+		h.writeRetryAfter(w, wait, reason)
+		return
+	}
+
+	var ok bool
+	if recoveryCode != "" {
+		ok, err = h.store.UseRecoveryCode(r.Context(), user.ID, recoveryCode)
+		if ok {
+			h.logger.Printf("user %d signed in with a recovery code", user.ID)
+		}
+	} else {
+		ok, err = h.useTOTPCode(r.Context(), user.ID, code, now)
+	}
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to verify code")
+		return
+	}
+	if !ok {
+		if err := h.store.FailLoginChallenge(r.Context(), challenge); err != nil {
+			h.logger.Printf("record login challenge failure: %v", err)
+		}
+		h.recordLoginFailure(r, &user, user.Email, ip, failures.Count+1)
+		h.writeError(w, http.StatusUnauthorized, "invalid code")
+		return
+	}
+
+	consumed, err := h.store.ConsumeLoginChallenge(r.Context(), challenge)
 	if err != nil {
 		h.writeError(w, http.StatusInternalServerError, "failed to create session")
 		return
 	}
+	if !consumed {
+		h.writeError(w, http.StatusUnauthorized, "invalid or expired challenge")
+		return
+	}
+	if err := h.store.RecordAttempt(r.Context(), attemptLogin, user.Email, ip, outcomeSuccess); err != nil {
+		h.logger.Printf("record login attempt: %v", err)
+	}
 
-	expiresAt := time.Now().Add(h.sessionTTL)
-	session, err := h.store.CreateSession(r.Context(), user.ID, token, expiresAt)
+	h.startSession(w, r, user)
+}
+
+func (h *Handler) startSession(w http.ResponseWriter, r *http.Request, user User) {
+	session, err := h.newSession(user)
 	if err != nil {
 		h.writeError(w, http.StatusInternalServerError, "failed to create session")
 		return
 	}
 
+	if err := h.store.CreateSession(r.Context(), user.ID, session, sessionClient(r)); err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to create session")
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, LoginResponse{User: user, Session: session})
+}
+
+func (h *Handler) handleRefreshToken(w http.ResponseWriter, r *http.Request) {
+	var input RefreshRequest
+	if err := h.decodeJSON(w, r, &input); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	refreshToken := strings.TrimSpace(input.RefreshToken)
+	if refreshToken == "" {
+		h.writeError(w, http.StatusBadRequest, "refresh_token is required")
+		return
+	}
+
+	user, session, err := h.store.RefreshSession(r.Context(), refreshToken, h.newSession)
+	if err != nil {
+		if errors.Is(err, ErrRefreshTokenReused) {
+			h.logger.Printf("refresh token reuse detected, session revoked")
+			h.writeError(w, http.StatusUnauthorized, "refresh token already used, session revoked")
+			return
+		}
+		if errors.Is(err, ErrInvalidRefreshToken) {
+			h.writeError(w, http.StatusUnauthorized, "invalid or expired refresh token")
+			return
+		}
+		h.writeError(w, http.StatusInternalServerError, "failed to refresh session")
+		return
+	}
+
 	h.writeJSON(w, http.StatusOK, LoginResponse{User: user, Session: session})
 }
 
@@ -165,10 +503,27 @@ func (h *Handler) handleForgotPassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ip := sessionClient(r).IP
+	now := time.Now()
+	requests, ipRequests, err := h.store.AttemptStats(r.Context(), attemptForgot, outcomeRequest, email, ip, now.Add(-h.forgotPolicy.Window))
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to create reset token")
+		return
+	}
+	if wait := h.forgotPolicy.retryAfter(requests, ipRequests, now); wait > 0 {
+		h.writeRetryAfter(w, wait, "too many reset requests, try again later")
+		return
+	}
+	if err := h.store.RecordAttempt(r.Context(), attemptForgot, email, ip, outcomeRequest); err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to create reset token")
+		return
+	}
+
+	message := "if the account exists, a reset email was sent"
 	user, err := h.store.GetUserByEmail(r.Context(), email)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			h.writeJSON(w, http.StatusOK, map[string]string{"message": "if the account exists, a reset token was generated"})
+			h.writeJSON(w, http.StatusOK, map[string]string{"message": message})
 			return
 		}
 		h.writeError(w, http.StatusInternalServerError, "failed to create reset token")
@@ -187,12 +542,23 @@ func (h *Handler) handleForgotPassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response := map[string]any{
-		"token":      token,
-		"expires_at": expiresAt,
-		"message":    "use token to reset password",
+	if err := h.sendTokenEmail(r.Context(), user, mail.TemplatePasswordReset, requestLanguage(r), token, expiresAt); err != nil {
+		h.logger.Printf("send password reset email to user %d: %v", user.ID, err)
+		if !h.devMode {
+			h.writeError(w, http.StatusInternalServerError, "failed to send reset email")
+			return
+		}
 	}
-	h.writeJSON(w, http.StatusOK, response)
+
+	if h.devMode {
+		h.writeJSON(w, http.StatusOK, map[string]any{
+			"token":      token,
+			"expires_at": expiresAt,
+			"message":    "use token to reset password",
+		})
+		return
+	}
+	h.writeJSON(w, http.StatusOK, map[string]string{"message": message})
 }
 
 func (h *Handler) handleResetPassword(w http.ResponseWriter, r *http.Request) {
@@ -207,8 +573,17 @@ func (h *Handler) handleResetPassword(w http.ResponseWriter, r *http.Request) {
 		h.writeError(w, http.StatusBadRequest, "token is required")
 		return
 	}
-	if len(input.NewPassword) < minPasswordLength {
-		h.writeError(w, http.StatusBadRequest, "password too short")
+
+	resetUser, err := h.store.GetPasswordResetUser(r.Context(), token)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.writeError(w, http.StatusBadRequest, "invalid or expired token")
+			return
+		}
+		h.writeError(w, http.StatusInternalServerError, "failed to reset password")
+		return
+	}
+	if !h.checkPasswordPolicy(w, input.NewPassword, resetUser.Email, resetUser.Name) {
 		return
 	}
 
@@ -218,7 +593,8 @@ func (h *Handler) handleResetPassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if _, err := h.store.ConsumePasswordReset(r.Context(), token, hash); err != nil {
+	user, err := h.store.ConsumePasswordReset(r.Context(), token, hash, sessionClient(r))
+	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			h.writeError(w, http.StatusBadRequest, "invalid or expired token")
 			return
@@ -226,10 +602,188 @@ func (h *Handler) handleResetPassword(w http.ResponseWriter, r *http.Request) {
 		h.writeError(w, http.StatusInternalServerError, "failed to reset password")
 		return
 	}
+	if err := h.store.RecordAttempt(r.Context(), attemptLogin, user.Email, sessionClient(r).IP, outcomeUnlock); err != nil {
+		h.logger.Printf("record account unlock: %v", err)
+	}
 
 	h.writeJSON(w, http.StatusOK, map[string]string{"message": "password reset successfully"})
 }
 
+func (h *Handler) handleUnlockAccount(w http.ResponseWriter, r *http.Request) {
+	var input UnlockAccountRequest
+	if err := h.decodeJSON(w, r, &input); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	token := strings.TrimSpace(input.Token)
+	if token == "" {
+		h.writeError(w, http.StatusBadRequest, "token is required")
+		return
+	}
+
+	if _, err := h.store.ConsumeAccountUnlock(r.Context(), token, sessionClient(r).IP); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.writeError(w, http.StatusBadRequest, "invalid or expired token")
+			return
+		}
+		h.writeError(w, http.StatusInternalServerError, "failed to unlock account")
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, map[string]string{"message": "account unlocked"})
+}
+
+func (h *Handler) handleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var input VerifyEmailRequest
+	if err := h.decodeJSON(w, r, &input); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	token := strings.TrimSpace(input.Token)
+	if token == "" {
+		h.writeError(w, http.StatusBadRequest, "token is required")
+		return
+	}
+
+	user, err := h.store.VerifyEmail(r.Context(), token)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.writeError(w, http.StatusBadRequest, "invalid or expired token")
+			return
+		}
+		h.writeError(w, http.StatusInternalServerError, "failed to verify email")
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, user)
+}
+
+func (h *Handler) handleResendVerification(w http.ResponseWriter, r *http.Request) {
+	var input ResendVerificationRequest
+	if err := h.decodeJSON(w, r, &input); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	email := normalizeEmail(input.Email)
+	if !isValidEmail(email) {
+		h.writeError(w, http.StatusBadRequest, "invalid email")
+		return
+	}
+
+	response := map[string]string{"message": "if the account exists and is not verified, a verification email was sent"}
+	user, err := h.store.GetUserByEmail(r.Context(), email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.writeJSON(w, http.StatusOK, response)
+			return
+		}
+		h.writeError(w, http.StatusInternalServerError, "failed to send verification email")
+		return
+	}
+	if user.EmailVerifiedAt != nil {
+		h.writeJSON(w, http.StatusOK, response)
+		return
+	}
+
+	count, last, err := h.store.RecentEmailVerifications(r.Context(), user.ID, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to send verification email")
+		return
+	}
+	if count >= maxVerificationEmailsPerDay || time.Since(last) < h.resendInterval {
+		h.logger.Printf("verification email to user %d throttled", user.ID)
+		h.writeJSON(w, http.StatusOK, response)
+		return
+	}
+
+	if err := h.sendVerification(r.Context(), user, requestLanguage(r)); err != nil {
+		h.logger.Printf("send verification email to user %d: %v", user.ID, err)
+		h.writeError(w, http.StatusInternalServerError, "failed to send verification email")
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, response)
+}
+
+func (h *Handler) handleLogout(w http.ResponseWriter, r *http.Request) {
+	user, sessionID, err := h.authenticateSession(r)
+	if err != nil {
+		h.writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	if _, err := h.store.RevokeSession(r.Context(), user.ID, sessionID); err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to log out")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) handleListSessions(w http.ResponseWriter, r *http.Request) {
+	user, sessionID, err := h.authenticateSession(r)
+	if err != nil {
+		h.writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	sessions, err := h.store.ListSessions(r.Context(), user.ID)
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to list sessions")
+		return
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == sessionID
+	}
+
+	h.writeJSON(w, http.StatusOK, map[string]any{"sessions": sessions})
+}
+
+func (h *Handler) handleRevokeSession(w http.ResponseWriter, r *http.Request) {
+	user, err := h.authenticate(r)
+	if err != nil {
+		h.writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id < 1 {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	revoked, err := h.store.RevokeSession(r.Context(), user.ID, id)
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to revoke session")
+		return
+	}
+	if !revoked {
+		h.writeError(w, http.StatusNotFound, "session not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) handleRevokeAllSessions(w http.ResponseWriter, r *http.Request) {
+	user, err := h.authenticate(r)
+	if err != nil {
+		h.writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	count, err := h.store.RevokeAllSessions(r.Context(), user.ID)
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to revoke sessions")
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, map[string]int64{"revoked": count})
+}
+
 func (h *Handler) handleGetProfile(w http.ResponseWriter, r *http.Request) {
 	user, err := h.authenticate(r)
 	if err != nil {
@@ -260,7 +814,7 @@ func (h *Handler) handleUpdateProfile(w http.ResponseWriter, r *http.Request) {
 
 	if input.Email != nil {
 		value := normalizeEmail(*input.Email)
-		if !isValidEmail(value) {
+		if !isDeliverableEmail(value) {
 			h.writeError(w, http.StatusBadRequest, "invalid email")
 			return
 		}
@@ -290,29 +844,367 @@ func (h *Handler) handleUpdateProfile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if user.Email != currentUser.Email {
+		if err := h.sendVerification(r.Context(), user, requestLanguage(r)); err != nil {
+			h.logger.Printf("send verification email to user %d: %v", user.ID, err)
+		}
+	}
+
 	h.writeJSON(w, http.StatusOK, user)
 }
 
+func (h *Handler) handleChangePassword(w http.ResponseWriter, r *http.Request) {
+	user, sessionID, err := h.authenticateSession(r)
+	if err != nil {
+		h.writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var input ChangePasswordRequest
+	if err := h.decodeJSON(w, r, &input); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if input.CurrentPassword == "" {
+		h.writeError(w, http.StatusBadRequest, "current_password is required")
+		return
+	}
+	if !h.checkCurrentPassword(w, r, user, input.CurrentPassword) {
+		return
+	}
+	if input.NewPassword == input.CurrentPassword {
+		h.writeError(w, http.StatusBadRequest, "new password must differ from current password")
+		return
+	}
+	if !h.checkPasswordPolicy(w, input.NewPassword, user.Email, user.Name) {
+		return
+	}
+
+	hash, err := hashPassword(input.NewPassword)
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to hash password")
+		return
+	}
+
+	revoked, err := h.store.ChangePassword(r.Context(), user.ID, hash, sessionID, input.RevokeOtherSessions, sessionClient(r))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.writeError(w, http.StatusNotFound, "user not found")
+			return
+		}
+		h.writeError(w, http.StatusInternalServerError, "failed to change password")
+		return
+	}
+	h.logger.Printf("user %d changed password, revoked %d other session(s)", user.ID, revoked)
+
+	h.writeJSON(w, http.StatusOK, map[string]any{
+		"message":          "password changed",
+		"revoked_sessions": revoked,
+	})
+}
+
+func (h *Handler) handleTwoFactorStatus(w http.ResponseWriter, r *http.Request) {
+	user, err := h.authenticate(r)
+	if err != nil {
+		h.writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	status, err := h.store.TwoFactorStatus(r.Context(), user.ID)
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to load two-factor status")
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, status)
+}
+
+func (h *Handler) handleTwoFactorEnroll(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.authenticateWithPassword(w, r)
+	if !ok {
+		return
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to start enrollment")
+		return
+	}
+	if err := h.store.StartTwoFactorEnrollment(r.Context(), user.ID, secret); err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to start enrollment")
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, TwoFactorEnrollment{
+		Secret:     secret,
+		OTPAuthURI: totpURI(h.totpIssuer, user.Email, secret),
+	})
+}
+
+func (h *Handler) handleTwoFactorConfirm(w http.ResponseWriter, r *http.Request) {
+	user, err := h.authenticate(r)
+	if err != nil {
+		h.writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var input TwoFactorConfirmRequest
+	if err := h.decodeJSON(w, r, &input); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	twoFactor, err := h.store.GetTwoFactor(r.Context(), user.ID)
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to confirm enrollment")
+		return
+	}
+	if twoFactor.PendingSecret == "" {
+		h.writeError(w, http.StatusBadRequest, "no pending enrollment")
+		return
+	}
+	step, ok := verifyTOTP(twoFactor.PendingSecret, input.Code, time.Now(), 0)
+	if !ok {
+		h.writeError(w, http.StatusBadRequest, "invalid code")
+		return
+	}
+
+	codes, err := generateRecoveryCodes()
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to confirm enrollment")
+		return
+	}
+	if err := h.store.ConfirmTwoFactor(r.Context(), user.ID, twoFactor.PendingSecret, step, codes, sessionClient(r)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.writeError(w, http.StatusConflict, "enrollment was restarted, scan the new secret")
+			return
+		}
+		h.writeError(w, http.StatusInternalServerError, "failed to confirm enrollment")
+		return
+	}
+	h.logger.Printf("user %d enabled two-factor authentication", user.ID)
+
+	h.writeJSON(w, http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+func (h *Handler) handleTwoFactorDisable(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.authenticateWithPassword(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.store.DisableTwoFactor(r.Context(), user.ID, sessionClient(r)); err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to disable two-factor authentication")
+		return
+	}
+	h.logger.Printf("user %d disabled two-factor authentication", user.ID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) authenticateWithPassword(w http.ResponseWriter, r *http.Request) (User, bool) {
+	user, err := h.authenticate(r)
+	if err != nil {
+		h.writeError(w, http.StatusUnauthorized, "unauthorized")
+		return User{}, false
+	}
+
+	var input TwoFactorPasswordRequest
+	if err := h.decodeJSON(w, r, &input); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return User{}, false
+	}
+	if input.Password == "" {
+		h.writeError(w, http.StatusBadRequest, "password is required")
+		return User{}, false
+	}
+	if !h.checkCurrentPassword(w, r, user, input.Password) {
+		return User{}, false
+	}
+	return user, true
+}
+
+func (h *Handler) checkCurrentPassword(w http.ResponseWriter, r *http.Request, user User, password string) bool {
+	ip := sessionClient(r).IP
+	now := time.Now()
+	failures, ipFailures, err := h.store.AttemptStats(r.Context(), attemptLogin, outcomeFailure, user.Email, ip, h.loginPolicy.since(now))
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to check login attempts")
+		return false
+	}
+	if wait, reason := h.loginPolicy.retryAfter(failures, ipFailures, now); wait > 0 {
+		h.writeRetryAfter(w, wait, reason)
+		return false
+	}
+
+	if err := verifyPassword(user.PasswordHash, password); err != nil {
+		h.recordLoginFailure(r, &user, user.Email, ip, failures.Count+1)
+		h.writeError(w, http.StatusForbidden, "invalid password")
+		return false
+	}
+	return true
+}
+
+func (h *Handler) checkPasswordPolicy(w http.ResponseWriter, password string, personal ...string) bool {
+	violations, err := h.passwordPolicy.Check(password, personal...)
+	if err != nil {
+		h.logger.Printf("breached password lookup: %v", err)
+	}
+	if len(violations) == 0 {
+		return true
+	}
+
+	h.writeJSON(w, http.StatusBadRequest, map[string]any{
+		"error":      violations[0].Message,
+		"code":       violations[0].Code,
+		"violations": violations,
+	})
+	return false
+}
+
+func (h *Handler) useTOTPCode(ctx context.Context, userID int64, code string, now time.Time) (bool, error) {
+	twoFactor, err := h.store.GetTwoFactor(ctx, userID)
+	if err != nil || !twoFactor.enabled() {
+		return false, err
+	}
+	step, ok := verifyTOTP(twoFactor.Secret, code, now, twoFactor.LastUsedStep)
+	if !ok {
+		return false, nil
+	}
+	return h.store.UseTOTPStep(ctx, userID, step)
+}
+
 func (h *Handler) authenticate(r *http.Request) (User, error) {
+	user, _, err := h.authenticateSession(r)
+	return user, err
+}
+
+func (h *Handler) authenticateSession(r *http.Request) (User, int64, error) {
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
-		return User{}, errUnauthorized
+		return User{}, 0, errUnauthorized
 	}
 
 	token := strings.TrimSpace(strings.TrimPrefix(authHeader, "Bearer "))
 	if token == "" {
-		return User{}, errUnauthorized
+		return User{}, 0, errUnauthorized
 	}
 
-	user, err := h.store.GetUserBySessionToken(r.Context(), token)
+	user, sessionID, err := h.store.GetUserBySessionToken(r.Context(), token)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return User{}, errUnauthorized
+			return User{}, 0, errUnauthorized
 		}
-		return User{}, err
+		return User{}, 0, err
+	}
+
+	return user, sessionID, nil
+}
+
+func (h *Handler) newSession(user User) (Session, error) {
+	now := time.Now()
+	expiresAt := now.Add(h.accessTTL)
+	accessToken, err := h.accessToken(user, now, expiresAt)
+	if err != nil {
+		return Session{}, err
+	}
+	refreshToken, err := generateToken(32)
+	if err != nil {
+		return Session{}, err
+	}
+
+	return Session{
+		Token:            accessToken,
+		ExpiresAt:        expiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: now.Add(h.refreshTTL),
+	}, nil
+}
+
+func (h *Handler) accessToken(user User, issuedAt, expiresAt time.Time) (string, error) {
+	if h.signer == nil {
+		return generateToken(32)
 	}
 
-	return user, nil
+	claims := jwt.Claims{
+		Subject: strconv.FormatInt(user.ID, 10),
+		Role:    user.Role,
+	}
+	claims.EmailVerified = user.EmailVerifiedAt != nil
+	if h.jwtClaims["email"] {
+		claims.Email = user.Email
+	}
+	if h.jwtClaims["name"] {
+		claims.Name = user.Name
+	}
+	return h.signer.Sign(claims, issuedAt, expiresAt)
+}
+
+func (h *Handler) sendVerification(ctx context.Context, user User, lang string) error {
+	token, err := generateToken(32)
+	if err != nil {
+		return err
+	}
+	expiresAt := time.Now().Add(h.verifyTTL)
+	if err := h.store.CreateEmailVerification(ctx, user, token, expiresAt); err != nil {
+		return err
+	}
+	return h.sendTokenEmail(ctx, user, mail.TemplateEmailVerification, lang, token, expiresAt)
+}
+
+func (h *Handler) sendTokenEmail(ctx context.Context, user User, template, lang, token string, expiresAt time.Time) error {
+	if h.mailer == nil {
+		return nil
+	}
+
+	msg, err := mail.Render(template, lang, mail.TokenEmail{Name: user.Name, Token: token, ExpiresAt: expiresAt})
+	if err != nil {
+		return err
+	}
+	msg.To = user.Email
+	return h.mailer.Send(ctx, msg)
+}
+
+func (h *Handler) recordLoginFailure(r *http.Request, user *User, email, ip string, failures int) {
+	if err := h.store.RecordAttempt(r.Context(), attemptLogin, email, ip, outcomeFailure); err != nil {
+		h.logger.Printf("record login attempt: %v", err)
+		return
+	}
+	if user == nil || failures != h.loginPolicy.MaxFailures {
+		return
+	}
+
+	h.logger.Printf("user %d locked after %d failed logins", user.ID, failures)
+	token, err := generateToken(32)
+	if err != nil {
+		h.logger.Printf("create unlock token: %v", err)
+		return
+	}
+	expiresAt := time.Now().Add(h.loginPolicy.UnlockTTL)
+	if err := h.store.CreateAccountUnlock(r.Context(), user.ID, token, expiresAt); err != nil {
+		h.logger.Printf("create unlock token: %v", err)
+		return
+	}
+	if err := h.sendTokenEmail(r.Context(), *user, mail.TemplateAccountUnlock, requestLanguage(r), token, expiresAt); err != nil {
+		h.logger.Printf("send unlock email to user %d: %v", user.ID, err)
+	}
+}
+
+func requestLanguage(r *http.Request) string {
+	return mail.MatchLanguage(r.Header.Get("Accept-Language"))
+}
+
+func sessionClient(r *http.Request) SessionClient {
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	return SessionClient{IP: ip, UserAgent: userAgent}
 }
 
 func (h *Handler) decodeJSON(w http.ResponseWriter, r *http.Request, dst any) error {
@@ -336,6 +1228,11 @@ func (h *Handler) writeJSON(w http.ResponseWriter, status int, v any) {
 	}
 }
 
+func (h *Handler) writeRetryAfter(w http.ResponseWriter, wait time.Duration, message string) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	h.writeError(w, http.StatusTooManyRequests, message)
+}
+
 func (h *Handler) writeError(w http.ResponseWriter, status int, message string) {
 	h.writeJSON(w, status, map[string]string{"error": message})
 }
@@ -348,6 +1245,18 @@ func isValidEmail(value string) bool {
 	return value != "" && strings.Contains(value, "@")
 }
 
+func isDeliverableEmail(value string) bool {
+	if len(value) > maxEmailLength {
+		return false
+	}
+	address, err := netmail.ParseAddress(value)
+	if err != nil || address.Address != value {
+		return false
+	}
+	domain := value[strings.LastIndex(value, "@")+1:]
+	return strings.Contains(domain, ".") && !strings.HasPrefix(domain, ".") && !strings.HasSuffix(domain, ".")
+}
+
 func hashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
@@ -0,0 +1,69 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+)
+
+const (
+	eventPasswordChanged   = "password_changed"
+	eventPasswordReset     = "password_reset"
+	eventTwoFactorEnabled  = "two_factor_enabled"
+	eventTwoFactorDisabled = "two_factor_disabled"
+)
+
+func (s *Store) ChangePassword(ctx context.Context, userID int64, newHash string, keepSessionID int64, revokeOthers bool, client SessionClient) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE users
+		SET password_hash = $1, updated_at = NOW()
+		WHERE id = $2
+	`, newHash, userID)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if affected == 0 {
+		return 0, sql.ErrNoRows
+	}
+
+	var revoked int64
+	if revokeOthers {
+		res, err := tx.ExecContext(ctx, `
+			UPDATE user_sessions
+			SET revoked_at = NOW()
+			WHERE user_id = $1 AND id <> $2 AND `+sessionAlive+`
+		`, userID, keepSessionID)
+		if err != nil {
+			return 0, err
+		}
+		if revoked, err = res.RowsAffected(); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := insertSecurityEvent(ctx, tx, userID, eventPasswordChanged, client); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return revoked, nil
+}
+
+func insertSecurityEvent(ctx context.Context, db execer, userID int64, kind string, client SessionClient) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO security_events (user_id, kind, ip, user_agent)
+		VALUES ($1, $2, $3, $4)
+	`, userID, kind, nullIfEmpty(client.IP), nullIfEmpty(client.UserAgent))
+	return err
+}
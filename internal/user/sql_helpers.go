@@ -8,3 +8,10 @@ func nullableString(value *string) sql.NullString {
 	}
 	return sql.NullString{String: *value, Valid: true}
 }
+
+func nullIfEmpty(value string) sql.NullString {
+	if value == "" {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: value, Valid: true}
+}
@@ -4,31 +4,49 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"strings"
 	"time"
 
+	"go_test/internal/auth"
+	"go_test/internal/metrics"
+
 	"github.com/jackc/pgx/v5/pgconn"
 )
 
-var ErrEmailExists = errors.New("email already exists")
+var (
+	ErrEmailExists         = errors.New("email already exists")
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reused")
+)
 
 const uniqueViolationCode = "23505"
 
+const userColumns = `id, email, name, role, password_hash, email_verified_at, created_at, updated_at`
+
+const sessionAlive = `revoked_at IS NULL AND GREATEST(expires_at, refresh_expires_at) > NOW()`
+
 type Store struct {
-	db *sql.DB
+	db              *sql.DB
+	plaintextTokens bool
 }
 
 func NewStore(db *sql.DB) *Store {
 	return &Store{db: db}
 }
 
+func (s *Store) WithPlaintextTokens() *Store {
+	s.plaintextTokens = true
+	return s
+}
+
 func (s *Store) CreateUser(ctx context.Context, email, name, passwordHash string) (User, error) {
 	var user User
 	row := s.db.QueryRowContext(ctx, `
 		INSERT INTO users (email, name, password_hash)
 		VALUES ($1, $2, $3)
-		RETURNING id, email, name, password_hash, created_at, updated_at
+		RETURNING `+userColumns+`
 	`, email, name, passwordHash)
-	if err := row.Scan(&user.ID, &user.Email, &user.Name, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt); err != nil {
+	if err := scanUser(row, &user); err != nil {
 		if isUniqueViolation(err) {
 			return User{}, ErrEmailExists
 		}
@@ -40,11 +58,11 @@ func (s *Store) CreateUser(ctx context.Context, email, name, passwordHash string
 func (s *Store) GetUserByEmail(ctx context.Context, email string) (User, error) {
 	var user User
 	row := s.db.QueryRowContext(ctx, `
-		SELECT id, email, name, password_hash, created_at, updated_at
+		SELECT `+userColumns+`
 		FROM users
 		WHERE email = $1
 	`, email)
-	if err := row.Scan(&user.ID, &user.Email, &user.Name, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt); err != nil {
+	if err := scanUser(row, &user); err != nil {
 		return User{}, err
 	}
 	return user, nil
@@ -53,11 +71,11 @@ func (s *Store) GetUserByEmail(ctx context.Context, email string) (User, error)
 func (s *Store) GetUserByID(ctx context.Context, id int64) (User, error) {
 	var user User
 	row := s.db.QueryRowContext(ctx, `
-		SELECT id, email, name, password_hash, created_at, updated_at
+		SELECT `+userColumns+`
 		FROM users
 		WHERE id = $1
 	`, id)
-	if err := row.Scan(&user.ID, &user.Email, &user.Name, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt); err != nil {
+	if err := scanUser(row, &user); err != nil {
 		return User{}, err
 	}
 	return user, nil
@@ -68,12 +86,13 @@ func (s *Store) UpdateUser(ctx context.Context, id int64, input UpdateProfileReq
 	row := s.db.QueryRowContext(ctx, `
 		UPDATE users
 		SET email = COALESCE($1, email),
+			email_verified_at = CASE WHEN $1 IS NULL OR $1 = email THEN email_verified_at END,
 			name = COALESCE($2, name),
 			updated_at = NOW()
 		WHERE id = $3
-		RETURNING id, email, name, password_hash, created_at, updated_at
+		RETURNING `+userColumns+`
 	`, nullableString(input.Email), nullableString(input.Name), id)
-	if err := row.Scan(&user.ID, &user.Email, &user.Name, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt); err != nil {
+	if err := scanUser(row, &user); err != nil {
 		if isUniqueViolation(err) {
 			return User{}, ErrEmailExists
 		}
@@ -82,56 +101,230 @@ func (s *Store) UpdateUser(ctx context.Context, id int64, input UpdateProfileReq
 	return user, nil
 }
 
-func (s *Store) CreateSession(ctx context.Context, userID int64, token string, expiresAt time.Time) (Session, error) {
-	row := s.db.QueryRowContext(ctx, `
-		INSERT INTO user_sessions (user_id, token, expires_at)
-		VALUES ($1, $2, $3)
-		RETURNING token, expires_at
-	`, userID, token, expiresAt)
+func (s *Store) CreateSession(ctx context.Context, userID int64, session Session, client SessionClient) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var sessionID int64
+	row := tx.QueryRowContext(ctx, `
+		INSERT INTO user_sessions (user_id, token_hash, expires_at, refresh_expires_at, ip, user_agent)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`, userID, auth.HashToken(session.Token), session.ExpiresAt, session.RefreshExpiresAt, nullIfEmpty(client.IP), nullIfEmpty(client.UserAgent))
+	if err := row.Scan(&sessionID); err != nil {
+		return err
+	}
 
-	var session Session
-	if err := row.Scan(&session.Token, &session.ExpiresAt); err != nil {
-		return Session{}, err
+	if err := s.writePlaintextToken(ctx, tx, "user_sessions", session.Token); err != nil {
+		return err
 	}
-	return session, nil
+	if err := insertRefreshToken(ctx, tx, sessionID, session); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (s *Store) GetUserBySessionToken(ctx context.Context, token string) (User, error) {
+func (s *Store) RefreshSession(ctx context.Context, refreshToken string, issue func(User) (Session, error)) (User, Session, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return User{}, Session{}, err
+	}
+	defer tx.Rollback()
+
+	tokenHash := auth.HashToken(refreshToken)
 	var user User
-	row := s.db.QueryRowContext(ctx, `
-		SELECT u.id, u.email, u.name, u.password_hash, u.created_at, u.updated_at
-		FROM user_sessions s
+	var sessionID int64
+	var used, active bool
+	row := tx.QueryRowContext(ctx, `
+		SELECT rt.session_id, rt.used_at IS NOT NULL, rt.expires_at > NOW() AND s.revoked_at IS NULL,
+			`+prefixedUserColumns("u")+`
+		FROM refresh_tokens rt
+		JOIN user_sessions s ON s.id = rt.session_id
 		JOIN users u ON u.id = s.user_id
-		WHERE s.token = $1 AND s.expires_at > NOW()
-	`, token)
-	if err := row.Scan(&user.ID, &user.Email, &user.Name, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt); err != nil {
-		return User{}, err
+		WHERE rt.token_hash = $1
+		FOR UPDATE OF rt, s
+	`, tokenHash)
+	if err := row.Scan(append([]any{&sessionID, &used, &active}, userFields(&user)...)...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return User{}, Session{}, ErrInvalidRefreshToken
+		}
+		return User{}, Session{}, err
 	}
-	return user, nil
+	if !active {
+		return User{}, Session{}, ErrInvalidRefreshToken
+	}
+	if used {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE user_sessions
+			SET revoked_at = NOW()
+			WHERE id = $1
+		`, sessionID); err != nil {
+			return User{}, Session{}, err
+		}
+		if err := tx.Commit(); err != nil {
+			return User{}, Session{}, err
+		}
+		return User{}, Session{}, ErrRefreshTokenReused
+	}
+
+	next, err := issue(user)
+	if err != nil {
+		return User{}, Session{}, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE refresh_tokens
+		SET used_at = NOW()
+		WHERE token_hash = $1
+	`, tokenHash); err != nil {
+		return User{}, Session{}, err
+	}
+	if err := insertRefreshToken(ctx, tx, sessionID, next); err != nil {
+		return User{}, Session{}, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE user_sessions
+		SET token_hash = $1, expires_at = $2, refresh_expires_at = $3, last_used_at = NOW()
+		WHERE id = $4
+	`, auth.HashToken(next.Token), next.ExpiresAt, next.RefreshExpiresAt, sessionID); err != nil {
+		return User{}, Session{}, err
+	}
+	if err := s.writePlaintextToken(ctx, tx, "user_sessions", next.Token); err != nil {
+		return User{}, Session{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return User{}, Session{}, err
+	}
+	return user, next, nil
+}
+
+func (s *Store) GetUserBySessionToken(ctx context.Context, token string) (User, int64, error) {
+	var user User
+	var sessionID int64
+	row := s.db.QueryRowContext(ctx, `
+		WITH session AS (
+			SELECT id, user_id, last_used_at
+			FROM user_sessions
+			WHERE token_hash = $1 AND expires_at > NOW() AND revoked_at IS NULL
+		),
+		touched AS (
+			UPDATE user_sessions
+			SET last_used_at = NOW()
+			FROM session
+			WHERE user_sessions.id = session.id AND session.last_used_at < NOW() - INTERVAL '1 minute'
+		)
+		SELECT session.id, `+prefixedUserColumns("u")+`
+		FROM session
+		JOIN users u ON u.id = session.user_id
+	`, auth.HashToken(token))
+	if err := row.Scan(append([]any{&sessionID}, userFields(&user)...)...); err != nil {
+		return User{}, 0, err
+	}
+	return user, sessionID, nil
+}
+
+func (s *Store) ListSessions(ctx context.Context, userID int64) ([]SessionInfo, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, created_at, last_used_at, expires_at, ip, user_agent
+		FROM user_sessions
+		WHERE user_id = $1 AND `+sessionAlive+`
+		ORDER BY last_used_at DESC, id DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []SessionInfo{}
+	for rows.Next() {
+		var session SessionInfo
+		if err := rows.Scan(&session.ID, &session.CreatedAt, &session.LastUsedAt, &session.ExpiresAt,
+			&session.IP, &session.UserAgent); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (s *Store) RevokeSession(ctx context.Context, userID, sessionID int64) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE user_sessions
+		SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND `+sessionAlive+`
+	`, sessionID, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+func (s *Store) RevokeAllSessions(ctx context.Context, userID int64) (int64, error) {
+	return revokeAllSessions(ctx, s.db, userID)
 }
 
 func (s *Store) CreatePasswordReset(ctx context.Context, userID int64, token string, expiresAt time.Time) error {
-	_, err := s.db.ExecContext(ctx, `
-		INSERT INTO password_resets (user_id, token, expires_at)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO password_resets (user_id, token_hash, expires_at)
 		VALUES ($1, $2, $3)
-	`, userID, token, expiresAt)
-	return err
+	`, userID, auth.HashToken(token), expiresAt); err != nil {
+		return err
+	}
+	if err := s.writePlaintextToken(ctx, tx, "password_resets", token); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (s *Store) ConsumePasswordReset(ctx context.Context, token, newHash string) (User, error) {
+func (s *Store) GetPasswordResetUser(ctx context.Context, token string) (User, error) {
+	var user User
+	row := s.db.QueryRowContext(ctx, `
+		SELECT `+prefixedUserColumns("u")+`
+		FROM password_resets pr
+		JOIN users u ON u.id = pr.user_id
+		WHERE pr.token_hash = $1 AND pr.expires_at > NOW() AND pr.consumed_at IS NULL
+	`, auth.HashToken(token))
+	if err := scanUser(row, &user); err != nil {
+		return User{}, err
+	}
+	return user, nil
+}
+
+func (s *Store) ConsumePasswordReset(ctx context.Context, token, newHash string, client SessionClient) (User, error) {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return User{}, err
 	}
 	defer tx.Rollback()
 
+	tokenHash := auth.HashToken(token)
 	var userID int64
 	row := tx.QueryRowContext(ctx, `
 		SELECT user_id
 		FROM password_resets
-		WHERE token = $1 AND expires_at > NOW()
+		WHERE token_hash = $1 AND expires_at > NOW() AND consumed_at IS NULL
 		FOR UPDATE
-	`, token)
+	`, tokenHash)
 	if err := row.Scan(&userID); err != nil {
 		return User{}, err
 	}
@@ -141,16 +334,25 @@ func (s *Store) ConsumePasswordReset(ctx context.Context, token, newHash string)
 		UPDATE users
 		SET password_hash = $1, updated_at = NOW()
 		WHERE id = $2
-		RETURNING id, email, name, password_hash, created_at, updated_at
+		RETURNING `+userColumns+`
 	`, newHash, userID)
-	if err := row.Scan(&user.ID, &user.Email, &user.Name, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt); err != nil {
+	if err := scanUser(row, &user); err != nil {
 		return User{}, err
 	}
 
 	if _, err := tx.ExecContext(ctx, `
-		DELETE FROM password_resets
-		WHERE token = $1
-	`, token); err != nil {
+		UPDATE password_resets
+		SET consumed_at = NOW()
+		WHERE token_hash = $1
+	`, tokenHash); err != nil {
+		return User{}, err
+	}
+
+	if _, err := revokeAllSessions(ctx, tx, userID); err != nil {
+		return User{}, err
+	}
+
+	if err := insertSecurityEvent(ctx, tx, userID, eventPasswordReset, client); err != nil {
 		return User{}, err
 	}
 
@@ -161,6 +363,127 @@ func (s *Store) ConsumePasswordReset(ctx context.Context, token, newHash string)
 	return user, nil
 }
 
+func (s *Store) CreateEmailVerification(ctx context.Context, user User, token string, expiresAt time.Time) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO email_verifications (user_id, email, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`, user.ID, user.Email, auth.HashToken(token), expiresAt)
+	return err
+}
+
+func (s *Store) RecentEmailVerifications(ctx context.Context, userID int64, since time.Time) (int, time.Time, error) {
+	var count int
+	var last sql.NullTime
+	row := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*), MAX(created_at)
+		FROM email_verifications
+		WHERE user_id = $1 AND created_at > $2
+	`, userID, since)
+	if err := row.Scan(&count, &last); err != nil {
+		return 0, time.Time{}, err
+	}
+	return count, last.Time, nil
+}
+
+func (s *Store) VerifyEmail(ctx context.Context, token string) (User, error) {
+	var user User
+	row := s.db.QueryRowContext(ctx, `
+		WITH verification AS (
+			UPDATE email_verifications v
+			SET consumed_at = NOW()
+			FROM users u
+			WHERE v.token_hash = $1 AND v.user_id = u.id AND v.email = u.email
+				AND v.expires_at > NOW() AND v.consumed_at IS NULL
+			RETURNING v.user_id
+		)
+		UPDATE users
+		SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW()
+		FROM verification
+		WHERE users.id = verification.user_id
+		RETURNING `+prefixedUserColumns("users")+`
+	`, auth.HashToken(token))
+	if err := scanUser(row, &user); err != nil {
+		return User{}, err
+	}
+	return user, nil
+}
+
+func (s *Store) CountActiveSessions(ctx context.Context) (int64, error) {
+	var count int64
+	row := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM user_sessions
+		WHERE `+sessionAlive+`
+	`)
+	if err := row.Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (s *Store) collectMetrics(ctx context.Context) ([]metrics.Sample, error) {
+	count, err := s.CountActiveSessions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return []metrics.Sample{
+		{Name: "user_sessions_active", Help: "Number of unexpired user sessions.", Type: metrics.TypeGauge, Value: float64(count)},
+	}, nil
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func (s *Store) writePlaintextToken(ctx context.Context, db execer, table, token string) error {
+	if !s.plaintextTokens {
+		return nil
+	}
+	_, err := db.ExecContext(ctx, `
+		UPDATE `+table+`
+		SET token = $1
+		WHERE token_hash = $2
+	`, token, auth.HashToken(token))
+	return err
+}
+
+func revokeAllSessions(ctx context.Context, db execer, userID int64) (int64, error) {
+	res, err := db.ExecContext(ctx, `
+		UPDATE user_sessions
+		SET revoked_at = NOW()
+		WHERE user_id = $1 AND `+sessionAlive+`
+	`, userID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func insertRefreshToken(ctx context.Context, db execer, sessionID int64, session Session) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO refresh_tokens (session_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+	`, sessionID, auth.HashToken(session.RefreshToken), session.RefreshExpiresAt)
+	return err
+}
+
+func prefixedUserColumns(alias string) string {
+	columns := strings.Split(userColumns, ", ")
+	for i, column := range columns {
+		columns[i] = alias + "." + column
+	}
+	return strings.Join(columns, ", ")
+}
+
+func scanUser(row *sql.Row, user *User) error {
+	return row.Scan(userFields(user)...)
+}
+
+func userFields(user *User) []any {
+	return []any{&user.ID, &user.Email, &user.Name, &user.Role, &user.PasswordHash, &user.EmailVerifiedAt,
+		&user.CreatedAt, &user.UpdatedAt}
+}
+
 func isUniqueViolation(err error) bool {
 	var pgErr *pgconn.PgError
 	if errors.As(err, &pgErr) {
@@ -0,0 +1,77 @@
+package user
+
+import (
+	"time"
+)
+
+const (
+	attemptLogin  = "login"
+	attemptForgot = "forgot"
+
+	outcomeFailure = "failure"
+	outcomeSuccess = "success"
+	outcomeUnlock  = "unlock"
+	outcomeRequest = "request"
+)
+
+type LoginPolicy struct {
+	MaxFailures   int
+	Lockout       time.Duration
+	Window        time.Duration
+	IPMaxFailures int
+	MaxDelay      time.Duration
+	UnlockTTL     time.Duration
+}
+
+type ForgotPolicy struct {
+	MaxPerEmail int
+	MaxPerIP    int
+	Window      time.Duration
+}
+
+type attemptStats struct {
+	Count int
+	First time.Time
+	Last  time.Time
+}
+
+func (p LoginPolicy) since(now time.Time) time.Time {
+	return now.Add(-max(p.Window, p.Lockout))
+}
+
+func (p LoginPolicy) retryAfter(account, ip attemptStats, now time.Time) (time.Duration, string) {
+	if p.MaxFailures > 0 && account.Count >= p.MaxFailures {
+		if wait := account.Last.Add(p.Lockout).Sub(now); wait > 0 {
+			return wait, "account temporarily locked, check your email to unlock it"
+		}
+	} else if account.Count > 0 {
+		if wait := account.Last.Add(progressiveDelay(account.Count, p.MaxDelay)).Sub(now); wait > 0 {
+			return wait, "too many failed attempts, try again later"
+		}
+	}
+	if p.IPMaxFailures > 0 && ip.Count >= p.IPMaxFailures {
+		if wait := ip.First.Add(p.Window).Sub(now); wait > 0 {
+			return wait, "too many failed attempts, try again later"
+		}
+	}
+	return 0, ""
+}
+
+func progressiveDelay(failures int, limit time.Duration) time.Duration {
+	if failures < 1 {
+		return 0
+	}
+	delay := time.Second << min(failures-1, 20)
+	return min(delay, limit)
+}
+
+func (p ForgotPolicy) retryAfter(email, ip attemptStats, now time.Time) time.Duration {
+	var wait time.Duration
+	if p.MaxPerEmail > 0 && email.Count >= p.MaxPerEmail {
+		wait = max(wait, email.First.Add(p.Window).Sub(now))
+	}
+	if p.MaxPerIP > 0 && ip.Count >= p.MaxPerIP {
+		wait = max(wait, ip.First.Add(p.Window).Sub(now))
+	}
+	return wait
+}
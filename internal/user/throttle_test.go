@@ -0,0 +1,68 @@
+package user
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProgressiveDelay(t *testing.T) {
+	cases := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{4, 8 * time.Second},
+		{6, 30 * time.Second},
+		{100, 30 * time.Second},
+	}
+	for _, tc := range cases {
+		if got := progressiveDelay(tc.failures, 30*time.Second); got != tc.want {
+			t.Fatalf("progressiveDelay(%d) = %s, want %s", tc.failures, got, tc.want)
+		}
+	}
+}
+
+func TestLoginPolicyRetryAfter(t *testing.T) {
+	policy := LoginPolicy{MaxFailures: 5, Lockout: 15 * time.Minute, Window: time.Hour, IPMaxFailures: 10, MaxDelay: 30 * time.Second}
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name    string
+		account attemptStats
+		ip      attemptStats
+		want    time.Duration
+	}{
+		{"no failures", attemptStats{}, attemptStats{}, 0},
+		{"progressive delay pending", attemptStats{Count: 3, Last: now.Add(-time.Second)}, attemptStats{}, 3 * time.Second},
+		{"progressive delay elapsed", attemptStats{Count: 3, Last: now.Add(-5 * time.Second)}, attemptStats{}, 0},
+		{"locked", attemptStats{Count: 5, Last: now.Add(-5 * time.Minute)}, attemptStats{}, 10 * time.Minute},
+		{"lockout expired", attemptStats{Count: 5, Last: now.Add(-16 * time.Minute)}, attemptStats{}, 0},
+		{"ip limit", attemptStats{}, attemptStats{Count: 10, First: now.Add(-20 * time.Minute)}, 40 * time.Minute},
+		{"ip below limit", attemptStats{}, attemptStats{Count: 9, First: now.Add(-20 * time.Minute)}, 0},
+	}
+	for _, tc := range cases {
+		if got, _ := policy.retryAfter(tc.account, tc.ip, now); got != tc.want {
+			t.Fatalf("%s: retryAfter = %s, want %s", tc.name, got, tc.want)
+		}
+	}
+
+	if since := policy.since(now); !since.Equal(now.Add(-time.Hour)) {
+		t.Fatalf("since = %s, want the longer of window and lockout", since)
+	}
+}
+
+func TestForgotPolicyRetryAfter(t *testing.T) {
+	policy := ForgotPolicy{MaxPerEmail: 3, MaxPerIP: 20, Window: time.Hour}
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	if got := policy.retryAfter(attemptStats{Count: 2, First: now.Add(-time.Minute)}, attemptStats{}, now); got != 0 {
+		t.Fatalf("below limit: retryAfter = %s, want 0", got)
+	}
+	email := attemptStats{Count: 3, First: now.Add(-50 * time.Minute)}
+	ip := attemptStats{Count: 20, First: now.Add(-30 * time.Minute)}
+	if got := policy.retryAfter(email, ip, now); got != 30*time.Minute {
+		t.Fatalf("both limits: retryAfter = %s, want the longer wait", got)
+	}
+}
@@ -0,0 +1,111 @@
+package user
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	totpDigits     = 6
+	totpModulus    = 1_000_000
+	totpPeriod     = 30
+	totpSkewSteps  = 1
+	totpSecretSize = 20
+
+	recoveryCodeCount = 10
+	recoveryCodeSize  = 5
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+type totpSecrets struct {
+	Secret        string
+	PendingSecret string
+	LastUsedStep  int64
+}
+
+func (s totpSecrets) enabled() bool {
+	return s.Secret != ""
+}
+
+func generateTOTPSecret() (string, error) {
+	buffer := make([]byte, totpSecretSize)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(buffer), nil
+}
+
+func totpURI(issuer, account, secret string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", strconv.Itoa(totpDigits))
+	query.Set("period", strconv.Itoa(totpPeriod))
+	return "otpauth://totp/" + url.PathEscape(issuer+":"+account) + "?" + query.Encode()
+}
+
+func totpStep(now time.Time) int64 {
+	return now.Unix() / totpPeriod
+}
+
+func totpCode(key []byte, step int64) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%totpModulus)
+}
+
+func verifyTOTP(secret, code string, now time.Time, lastUsedStep int64) (int64, bool) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return 0, false
+	}
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) != totpDigits {
+		return 0, false
+	}
+
+	current := totpStep(now)
+	for step := current - totpSkewSteps; step <= current+totpSkewSteps; step++ {
+		if step <= lastUsedStep {
+			continue
+		}
+		if hmac.Equal([]byte(totpCode(key, step)), []byte(code)) {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+func generateRecoveryCodes() ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		buffer := make([]byte, recoveryCodeSize)
+		if _, err := rand.Read(buffer); err != nil {
+			return nil, err
+		}
+		code := hex.EncodeToString(buffer)
+		codes[i] = code[:len(code)/2] + "-" + code[len(code)/2:]
+	}
+	return codes, nil
+}
+
+func normalizeRecoveryCode(value string) string {
+	return strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(strings.TrimSpace(value)))
+}
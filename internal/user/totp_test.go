@@ -0,0 +1,92 @@
+package user
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 附录 B 的 SHA1 测试向量，取低 6 位
+var rfc6238Secret = totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCodeVectors(t *testing.T) {
+	cases := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tc := range cases {
+		got := totpCode([]byte("12345678901234567890"), totpStep(time.Unix(tc.unix, 0)))
+		if got != tc.want {
+			t.Fatalf("totpCode(%d) = %s, want %s", tc.unix, got, tc.want)
+		}
+	}
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := totpStep(now)
+
+	step, ok := verifyTOTP(rfc6238Secret, "050471", now, 0)
+	if !ok || step != current {
+		t.Fatalf("current code: step=%d ok=%v", step, ok)
+	}
+	if _, ok := verifyTOTP(strings.ToLower(rfc6238Secret), "050 471", now, 0); !ok {
+		t.Fatal("expected lowercase secret and spaced code to verify")
+	}
+	// 前后各容忍一个时间步
+	if _, ok := verifyTOTP(rfc6238Secret, "050471", now.Add(totpPeriod*time.Second), 0); !ok {
+		t.Fatal("expected previous step to verify")
+	}
+	if _, ok := verifyTOTP(rfc6238Secret, "050471", now.Add(2*totpPeriod*time.Second), 0); ok {
+		t.Fatal("expected code two steps old to be rejected")
+	}
+	// 已使用过的时间步不能重放
+	if _, ok := verifyTOTP(rfc6238Secret, "050471", now, current); ok {
+		t.Fatal("expected replayed code to be rejected")
+	}
+	for _, code := range []string{"", "12345", "1234567", "000000"} {
+		if _, ok := verifyTOTP(rfc6238Secret, code, now, 0); ok {
+			t.Fatalf("expected %q to be rejected", code)
+		}
+	}
+	if _, ok := verifyTOTP("not base32!", "050471", now, 0); ok {
+		t.Fatal("expected invalid secret to be rejected")
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	got := totpURI("go-api", "demo@example.com", "ABC")
+	want := "otpauth://totp/go-api:demo@example.com?algorithm=SHA1&digits=6&issuer=go-api&period=30&secret=ABC"
+	if got != want {
+		t.Fatalf("totpURI = %s, want %s", got, want)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := generateRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("got %d codes, want %d", len(codes), recoveryCodeCount)
+	}
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if len(code) != 2*recoveryCodeSize+1 || code[recoveryCodeSize] != '-' {
+			t.Fatalf("unexpected code format %q", code)
+		}
+		if seen[code] {
+			t.Fatalf("duplicate code %q", code)
+		}
+		seen[code] = true
+	}
+	if normalizeRecoveryCode(" ABCDE-12345 ") != "abcde12345" {
+		t.Fatalf("normalizeRecoveryCode = %q", normalizeRecoveryCode(" ABCDE-12345 "))
+	}
+}
@@ -0,0 +1,215 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"go_test/internal/auth"
+)
+
+func (s *Store) GetTwoFactor(ctx context.Context, userID int64) (totpSecrets, error) {
+	var secrets totpSecrets
+	var secret, pending sql.NullString
+	var lastUsedStep sql.NullInt64
+	row := s.db.QueryRowContext(ctx, `
+		SELECT secret, pending_secret, last_used_step
+		FROM user_totp
+		WHERE user_id = $1
+	`, userID)
+	if err := row.Scan(&secret, &pending, &lastUsedStep); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return totpSecrets{}, nil
+		}
+		return totpSecrets{}, err
+	}
+	secrets.Secret = secret.String
+	secrets.PendingSecret = pending.String
+	secrets.LastUsedStep = lastUsedStep.Int64
+	return secrets, nil
+}
+
+func (s *Store) TwoFactorStatus(ctx context.Context, userID int64) (TwoFactorStatus, error) {
+	var status TwoFactorStatus
+	var enabledAt sql.NullTime
+	row := s.db.QueryRowContext(ctx, `
+		SELECT t.enabled_at,
+			(SELECT COUNT(*) FROM totp_recovery_codes c WHERE c.user_id = $1 AND c.used_at IS NULL)
+		FROM users u
+		LEFT JOIN user_totp t ON t.user_id = u.id AND t.secret IS NOT NULL
+		WHERE u.id = $1
+	`, userID)
+	if err := row.Scan(&enabledAt, &status.RecoveryCodesRemaining); err != nil {
+		return TwoFactorStatus{}, err
+	}
+	if enabledAt.Valid {
+		status.Enabled = true
+		status.EnabledAt = &enabledAt.Time
+	}
+	return status, nil
+}
+
+func (s *Store) StartTwoFactorEnrollment(ctx context.Context, userID int64, secret string) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO user_totp (user_id, pending_secret, pending_created_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (user_id) DO UPDATE
+		SET pending_secret = EXCLUDED.pending_secret,
+			pending_created_at = EXCLUDED.pending_created_at
+	`, userID, secret)
+	return err
+}
+
+func (s *Store) ConfirmTwoFactor(ctx context.Context, userID int64, pendingSecret string, step int64, recoveryCodes []string, client SessionClient) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE user_totp
+		SET secret = pending_secret,
+			enabled_at = NOW(),
+			last_used_step = $3,
+			pending_secret = NULL,
+			pending_created_at = NULL
+		WHERE user_id = $1 AND pending_secret = $2
+	`, userID, pendingSecret, step)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM totp_recovery_codes
+		WHERE user_id = $1
+	`, userID); err != nil {
+		return err
+	}
+	for _, code := range recoveryCodes {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO totp_recovery_codes (user_id, code_hash)
+			VALUES ($1, $2)
+		`, userID, auth.HashToken(normalizeRecoveryCode(code))); err != nil {
+			return err
+		}
+	}
+
+	if err := insertSecurityEvent(ctx, tx, userID, eventTwoFactorEnabled, client); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *Store) DisableTwoFactor(ctx context.Context, userID int64, client SessionClient) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, query := range []string{
+		`DELETE FROM user_totp WHERE user_id = $1`,
+		`DELETE FROM totp_recovery_codes WHERE user_id = $1`,
+		`DELETE FROM login_challenges WHERE user_id = $1`,
+	} {
+		if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+			return err
+		}
+	}
+
+	if err := insertSecurityEvent(ctx, tx, userID, eventTwoFactorDisabled, client); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *Store) UseTOTPStep(ctx context.Context, userID, step int64) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE user_totp
+		SET last_used_step = $2
+		WHERE user_id = $1 AND secret IS NOT NULL
+			AND (last_used_step IS NULL OR last_used_step < $2)
+	`, userID, step)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+func (s *Store) UseRecoveryCode(ctx context.Context, userID int64, code string) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE totp_recovery_codes
+		SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`, userID, auth.HashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+func (s *Store) CreateLoginChallenge(ctx context.Context, userID int64, token string, expiresAt time.Time) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO login_challenges (user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+	`, userID, auth.HashToken(token), expiresAt)
+	return err
+}
+
+func (s *Store) GetLoginChallenge(ctx context.Context, token string, maxAttempts int) (User, error) {
+	var user User
+	row := s.db.QueryRowContext(ctx, `
+		SELECT `+prefixedUserColumns("u")+`
+		FROM login_challenges c
+		JOIN users u ON u.id = c.user_id
+		WHERE c.token_hash = $1 AND c.expires_at > NOW() AND c.consumed_at IS NULL AND c.attempts < $2
+	`, auth.HashToken(token), maxAttempts)
+	if err := scanUser(row, &user); err != nil {
+		return User{}, err
+	}
+	return user, nil
+}
+
+func (s *Store) FailLoginChallenge(ctx context.Context, token string) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE login_challenges
+		SET attempts = attempts + 1
+		WHERE token_hash = $1
+	`, auth.HashToken(token))
+	return err
+}
+
+func (s *Store) ConsumeLoginChallenge(ctx context.Context, token string) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE login_challenges
+		SET consumed_at = NOW()
+		WHERE token_hash = $1 AND expires_at > NOW() AND consumed_at IS NULL
+	`, auth.HashToken(token))
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
@@ -0,0 +1,13 @@
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS due_at TIMESTAMPTZ;
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS parent_id BIGINT REFERENCES todos(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_todos_parent_id ON todos(parent_id);
+
+CREATE TABLE IF NOT EXISTS todo_templates (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE CHECK (char_length(name) > 0),
+    description TEXT NOT NULL DEFAULT '',
+    items JSONB NOT NULL DEFAULT '[]'::jsonb,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
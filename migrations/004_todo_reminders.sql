@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS todo_reminders (
+    id BIGSERIAL PRIMARY KEY,
+    todo_id BIGINT NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+    remind_at TIMESTAMPTZ,
+    offset_seconds BIGINT CHECK (offset_seconds >= 0),
+    recipient TEXT NOT NULL DEFAULT '',
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMPTZ,
+    sent_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK ((remind_at IS NULL) <> (offset_seconds IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_todo_reminders_todo_id ON todo_reminders(todo_id);
+CREATE INDEX IF NOT EXISTS idx_todo_reminders_pending ON todo_reminders(remind_at) WHERE sent_at IS NULL;
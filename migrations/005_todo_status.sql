@@ -0,0 +1,20 @@
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'open';
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS snoozed_until TIMESTAMPTZ;
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS completed_at TIMESTAMPTZ;
+
+-- 旧数据只有 done 布尔值，完成时间用 updated_at 近似
+UPDATE todos
+SET status = 'done',
+    completed_at = COALESCE(completed_at, updated_at)
+WHERE done AND status = 'open';
+
+ALTER TABLE todos DROP CONSTRAINT IF EXISTS todos_status_check;
+ALTER TABLE todos ADD CONSTRAINT todos_status_check
+    CHECK (status IN ('open', 'in_progress', 'blocked', 'done', 'cancelled', 'snoozed'));
+
+ALTER TABLE todos DROP CONSTRAINT IF EXISTS todos_snoozed_until_check;
+ALTER TABLE todos ADD CONSTRAINT todos_snoozed_until_check
+    CHECK ((status = 'snoozed') = (snoozed_until IS NOT NULL));
+
+CREATE INDEX IF NOT EXISTS idx_todos_status ON todos(status);
+CREATE INDEX IF NOT EXISTS idx_todos_snoozed_until ON todos(snoozed_until) WHERE status = 'snoozed';
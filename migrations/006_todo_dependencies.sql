@@ -0,0 +1,13 @@
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS list_name TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_todos_list_name ON todos(list_name);
+
+CREATE TABLE IF NOT EXISTS todo_dependencies (
+    todo_id BIGINT NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+    blocker_id BIGINT NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (todo_id, blocker_id),
+    CHECK (todo_id <> blocker_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_todo_dependencies_blocker_id ON todo_dependencies(blocker_id);
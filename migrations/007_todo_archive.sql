@@ -0,0 +1,12 @@
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS user_id BIGINT REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_todos_user_id ON todos(user_id);
+CREATE INDEX IF NOT EXISTS idx_todos_archivable ON todos(user_id, status) WHERE archived_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS archive_policies (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    after_days INT NOT NULL CHECK (after_days > 0),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
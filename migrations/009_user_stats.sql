@@ -0,0 +1,9 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'user';
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users ADD CONSTRAINT users_role_check CHECK (role IN ('user', 'admin'));
+
+-- 个人统计按 user_id 过滤，再按完成时间、截止时间聚合
+CREATE INDEX IF NOT EXISTS idx_todos_user_completed_at ON todos(user_id, completed_at) WHERE completed_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_todos_user_open_due_at ON todos(user_id, due_at) WHERE status NOT IN ('done', 'cancelled');
+CREATE INDEX IF NOT EXISTS idx_todos_user_list_name ON todos(user_id, list_name);
@@ -0,0 +1,68 @@
+-- 由触发器增量维护的 todo 计数，stats-api 读取这一行而不是每次全表 COUNT(*)
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS todo_counters (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    total BIGINT NOT NULL DEFAULT 0,
+    done BIGINT NOT NULL DEFAULT 0,
+    archived BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE OR REPLACE FUNCTION todo_counters_apply() RETURNS TRIGGER AS $$
+DECLARE
+    d_total BIGINT := 0;
+    d_done BIGINT := 0;
+    d_archived BIGINT := 0;
+BEGIN
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        d_total := d_total + 1;
+        d_done := d_done + NEW.done::int;
+        d_archived := d_archived + (NEW.archived_at IS NOT NULL)::int;
+    END IF;
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        d_total := d_total - 1;
+        d_done := d_done - OLD.done::int;
+        d_archived := d_archived - (OLD.archived_at IS NOT NULL)::int;
+    END IF;
+
+    UPDATE todo_counters
+    SET total = total + d_total,
+        done = done + d_done,
+        archived = archived + d_archived,
+        updated_at = NOW()
+    WHERE id;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS todo_counters_insert_delete ON todos;
+CREATE TRIGGER todo_counters_insert_delete
+    AFTER INSERT OR DELETE ON todos
+    FOR EACH ROW EXECUTE FUNCTION todo_counters_apply();
+
+-- 只有影响计数的列变化时才更新计数行，减少热点行争用
+DROP TRIGGER IF EXISTS todo_counters_update ON todos;
+CREATE TRIGGER todo_counters_update
+    AFTER UPDATE OF done, archived_at ON todos
+    FOR EACH ROW
+    WHEN (OLD.done IS DISTINCT FROM NEW.done
+        OR (OLD.archived_at IS NULL) IS DISTINCT FROM (NEW.archived_at IS NULL))
+    EXECUTE FUNCTION todo_counters_apply();
+
+-- 初始化时阻止并发写入，保证初始值与触发器增量衔接
+LOCK TABLE todos IN SHARE MODE;
+
+INSERT INTO todo_counters (id, total, done, archived)
+SELECT TRUE,
+    COUNT(*),
+    COUNT(*) FILTER (WHERE done),
+    COUNT(*) FILTER (WHERE archived_at IS NOT NULL)
+FROM todos
+ON CONFLICT (id) DO UPDATE
+SET total = EXCLUDED.total,
+    done = EXCLUDED.done,
+    archived = EXCLUDED.archived,
+    updated_at = NOW();
+
+COMMIT;
@@ -0,0 +1,56 @@
+-- todo 状态/清单的变更历史，burndown 与累积流图按历史还原过去每天的状态
+BEGIN;
+
+-- 不引用 todos：todo 被删除后历史仍需保留，删除本身记为 status = 'deleted'
+CREATE TABLE IF NOT EXISTS todo_status_history (
+    id BIGSERIAL PRIMARY KEY,
+    todo_id BIGINT NOT NULL,
+    status TEXT NOT NULL,
+    list_name TEXT,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_todo_status_history_todo ON todo_status_history(todo_id, changed_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_todo_status_history_changed_at ON todo_status_history(changed_at);
+
+CREATE OR REPLACE FUNCTION todo_status_history_record() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO todo_status_history (todo_id, status, list_name)
+        VALUES (OLD.id, 'deleted', OLD.list_name);
+    ELSE
+        INSERT INTO todo_status_history (todo_id, status, list_name)
+        VALUES (NEW.id, NEW.status, NEW.list_name);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS todo_status_history_insert_delete ON todos;
+CREATE TRIGGER todo_status_history_insert_delete
+    AFTER INSERT OR DELETE ON todos
+    FOR EACH ROW EXECUTE FUNCTION todo_status_history_record();
+
+DROP TRIGGER IF EXISTS todo_status_history_update ON todos;
+CREATE TRIGGER todo_status_history_update
+    AFTER UPDATE OF status, list_name ON todos
+    FOR EACH ROW
+    WHEN (OLD.status IS DISTINCT FROM NEW.status
+        OR OLD.list_name IS DISTINCT FROM NEW.list_name)
+    EXECUTE FUNCTION todo_status_history_record();
+
+-- 回填已有数据：只能根据 created_at/completed_at/updated_at 近似还原
+LOCK TABLE todos IN SHARE MODE;
+
+INSERT INTO todo_status_history (todo_id, status, list_name, changed_at)
+SELECT t.id, 'open', t.list_name, t.created_at
+FROM todos t
+WHERE NOT EXISTS (SELECT 1 FROM todo_status_history h WHERE h.todo_id = t.id);
+
+INSERT INTO todo_status_history (todo_id, status, list_name, changed_at)
+SELECT t.id, t.status, t.list_name, COALESCE(t.completed_at, t.updated_at)
+FROM todos t
+WHERE t.status <> 'open'
+    AND NOT EXISTS (SELECT 1 FROM todo_status_history h WHERE h.todo_id = t.id AND h.status <> 'open');
+
+COMMIT;
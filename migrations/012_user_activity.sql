@@ -0,0 +1,7 @@
+-- 重置记录改为标记已使用而不是删除，保留请求量用于统计
+ALTER TABLE password_resets ADD COLUMN IF NOT EXISTS consumed_at TIMESTAMPTZ;
+
+-- /stats/users 按创建时间分桶统计注册、登录与重置请求
+CREATE INDEX IF NOT EXISTS idx_users_created_at ON users(created_at);
+CREATE INDEX IF NOT EXISTS idx_user_sessions_created_at ON user_sessions(created_at, user_id);
+CREATE INDEX IF NOT EXISTS idx_password_resets_created_at ON password_resets(created_at);
@@ -0,0 +1,8 @@
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS priority TEXT NOT NULL DEFAULT 'medium';
+
+ALTER TABLE todos DROP CONSTRAINT IF EXISTS todos_priority_check;
+ALTER TABLE todos ADD CONSTRAINT todos_priority_check
+    CHECK (priority IN ('low', 'medium', 'high', 'urgent'));
+
+-- 排行榜按完成时间窗口聚合每个用户的完成数/积分
+CREATE INDEX IF NOT EXISTS idx_todos_completed_user ON todos(completed_at, user_id) WHERE status = 'done';
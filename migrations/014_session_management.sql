@@ -0,0 +1,10 @@
+-- 会话记录登录时的 IP/User-Agent 与最近使用时间；登出与吊销只标记 revoked_at，
+-- 保留登录记录供 stats-api 统计活跃用户
+ALTER TABLE user_sessions ADD COLUMN IF NOT EXISTS last_used_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+ALTER TABLE user_sessions ADD COLUMN IF NOT EXISTS ip TEXT;
+ALTER TABLE user_sessions ADD COLUMN IF NOT EXISTS user_agent TEXT;
+ALTER TABLE user_sessions ADD COLUMN IF NOT EXISTS revoked_at TIMESTAMPTZ;
+
+UPDATE user_sessions SET last_used_at = created_at WHERE last_used_at > created_at;
+
+CREATE INDEX IF NOT EXISTS idx_user_sessions_active ON user_sessions(user_id, last_used_at DESC) WHERE revoked_at IS NULL;
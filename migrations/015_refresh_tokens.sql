@@ -0,0 +1,15 @@
+-- 每次登录的会话即一个刷新令牌家族：access token 仍保存在 user_sessions.token 中且有效期较短，
+-- 刷新时轮换 access token 与 refresh token；refresh token 只保存 SHA-256 摘要
+ALTER TABLE user_sessions ADD COLUMN IF NOT EXISTS refresh_expires_at TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    session_id BIGINT NOT NULL REFERENCES user_sessions(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    -- 已被轮换的 token 不删除，再次出现即视为泄露
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_session_id ON refresh_tokens(session_id);
@@ -0,0 +1,40 @@
+-- 会话与重置 token 改为只保存 SHA-256 摘要（第一阶段，可在旧版本服务运行时执行）：
+-- 新增 token_hash 并回填，旧版本服务写入的明文 token 由触发器同步计算摘要，
+-- 新版本服务按摘要查询，升级期间以 TOKEN_DUAL_WRITE=true 同时写入明文供旧实例查询；
+-- 全部实例升级并关闭双写后再执行 017 清除明文（顺序见 README）
+ALTER TABLE user_sessions ADD COLUMN IF NOT EXISTS token_hash TEXT;
+ALTER TABLE password_resets ADD COLUMN IF NOT EXISTS token_hash TEXT;
+
+ALTER TABLE user_sessions ALTER COLUMN token DROP NOT NULL;
+ALTER TABLE password_resets ALTER COLUMN token DROP NOT NULL;
+
+CREATE OR REPLACE FUNCTION hash_plaintext_token() RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.token IS NOT NULL THEN
+        NEW.token_hash := encode(sha256(convert_to(NEW.token, 'UTF8')), 'hex');
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS user_sessions_hash_token ON user_sessions;
+CREATE TRIGGER user_sessions_hash_token
+    BEFORE INSERT OR UPDATE OF token ON user_sessions
+    FOR EACH ROW EXECUTE FUNCTION hash_plaintext_token();
+
+DROP TRIGGER IF EXISTS password_resets_hash_token ON password_resets;
+CREATE TRIGGER password_resets_hash_token
+    BEFORE INSERT OR UPDATE OF token ON password_resets
+    FOR EACH ROW EXECUTE FUNCTION hash_plaintext_token();
+
+-- 回填已有记录；索引并发创建，不阻塞写入（psql -f 逐条自动提交，不能放进事务）
+UPDATE user_sessions
+SET token_hash = encode(sha256(convert_to(token, 'UTF8')), 'hex')
+WHERE token_hash IS NULL AND token IS NOT NULL;
+
+UPDATE password_resets
+SET token_hash = encode(sha256(convert_to(token, 'UTF8')), 'hex')
+WHERE token_hash IS NULL AND token IS NOT NULL;
+
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS idx_user_sessions_token_hash ON user_sessions(token_hash);
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS idx_password_resets_token_hash ON password_resets(token_hash);
@@ -0,0 +1,10 @@
+-- 第二阶段：所有实例都已按 token_hash 读写后执行，删除明文 token 列与同步触发器
+DROP TRIGGER IF EXISTS user_sessions_hash_token ON user_sessions;
+DROP TRIGGER IF EXISTS password_resets_hash_token ON password_resets;
+DROP FUNCTION IF EXISTS hash_plaintext_token();
+
+ALTER TABLE user_sessions DROP COLUMN IF EXISTS token;
+ALTER TABLE password_resets DROP COLUMN IF EXISTS token;
+
+ALTER TABLE user_sessions ALTER COLUMN token_hash SET NOT NULL;
+ALTER TABLE password_resets ALTER COLUMN token_hash SET NOT NULL;
@@ -0,0 +1,26 @@
+-- 登录与忘记密码的尝试记录，多个 user-api 实例共享同一份计数。按请求中的邮箱而不是用户 ID 记录，
+-- 不存在的账户同样会被限流，避免通过响应差异探测账户是否存在
+CREATE TABLE IF NOT EXISTS auth_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    kind TEXT NOT NULL CHECK (kind IN ('login', 'forgot')),
+    email TEXT NOT NULL,
+    ip TEXT,
+    -- failure 计入锁定；success 与 unlock 把该邮箱之前的失败清零；request 用于忘记密码计数
+    outcome TEXT NOT NULL CHECK (outcome IN ('failure', 'success', 'unlock', 'request')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_attempts_email ON auth_attempts(kind, email, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_auth_attempts_ip ON auth_attempts(kind, ip, created_at DESC);
+
+-- 账户被锁定时通过邮件发送的解锁 token，只保存 SHA-256 摘要
+CREATE TABLE IF NOT EXISTS account_unlocks (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    consumed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_account_unlocks_user_id ON account_unlocks(user_id);
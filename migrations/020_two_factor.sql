@@ -0,0 +1,35 @@
+-- TOTP 两步验证。secret 需要参与验证码计算，无法像 token 一样只保存摘要；
+-- pending_secret 是尚未用首个验证码确认的新密钥，重新绑定时确认前旧的 secret 继续生效
+CREATE TABLE IF NOT EXISTS user_totp (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret TEXT,
+    enabled_at TIMESTAMPTZ,
+    -- 最近一次通过校验的时间步，同一验证码在有效期内不能重复使用
+    last_used_step BIGINT,
+    pending_secret TEXT,
+    pending_created_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- 一次性恢复码，只保存 SHA-256 摘要；重新绑定或关闭两步验证时整体删除
+CREATE TABLE IF NOT EXISTS totp_recovery_codes (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, code_hash)
+);
+
+-- 密码校验通过后签发的登录挑战，凭它和验证码换取会话；attempts 记录错误验证码次数
+CREATE TABLE IF NOT EXISTS login_challenges (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    consumed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_challenges_user_id ON login_challenges(user_id);
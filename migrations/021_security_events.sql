@@ -0,0 +1,11 @@
+-- 账户安全相关操作的审计记录（修改/重置密码、开启/关闭两步验证），保留请求来源便于用户或管理员追查
+CREATE TABLE IF NOT EXISTS security_events (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('password_changed', 'password_reset', 'two_factor_enabled', 'two_factor_disabled')),
+    ip TEXT,
+    user_agent TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_security_events_user_id ON security_events(user_id, created_at DESC);
@@ -0,0 +1,2 @@
+-- 调度器认领提醒时写入租约，发送在事务外进行；租约过期的提醒可被重新认领
+ALTER TABLE todo_reminders ADD COLUMN IF NOT EXISTS claimed_until TIMESTAMPTZ;
@@ -0,0 +1,67 @@
+-- 计数拆成多行分片：触发器按当前连接的 pid 选分片累加，并发写入分散到不同行，不再争用同一热点行。
+-- 一个事务始终落在同一分片上，不会因为多个分片的加锁顺序不同而死锁。
+-- stats-api 读取时对分片求和，对账任务锁住全部分片后校正。
+-- 执行顺序：先把所有 stats-api 升级到读取分片的版本（分片表不存在时它回退到全表统计、跳过对账），
+-- 再执行本迁移；旧版本 stats-api 读写的 todo_counters 表会在这里删除
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS todo_counter_shards (
+    shard SMALLINT PRIMARY KEY,
+    total BIGINT NOT NULL DEFAULT 0,
+    done BIGINT NOT NULL DEFAULT 0,
+    archived BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE OR REPLACE FUNCTION todo_counters_apply() RETURNS TRIGGER AS $$
+DECLARE
+    d_total BIGINT := 0;
+    d_done BIGINT := 0;
+    d_archived BIGINT := 0;
+BEGIN
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        d_total := d_total + 1;
+        d_done := d_done + NEW.done::int;
+        d_archived := d_archived + (NEW.archived_at IS NOT NULL)::int;
+    END IF;
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        d_total := d_total - 1;
+        d_done := d_done - OLD.done::int;
+        d_archived := d_archived - (OLD.archived_at IS NOT NULL)::int;
+    END IF;
+
+    INSERT INTO todo_counter_shards AS c (shard, total, done, archived)
+    VALUES (pg_backend_pid() % 16, d_total, d_done, d_archived)
+    ON CONFLICT (shard) DO UPDATE
+    SET total = c.total + EXCLUDED.total,
+        done = c.done + EXCLUDED.done,
+        archived = c.archived + EXCLUDED.archived,
+        updated_at = NOW();
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- 切换期间阻止并发写入，精确计数整体写入 0 号分片，其余分片清零（重复执行也保持正确）
+LOCK TABLE todos IN SHARE MODE;
+
+INSERT INTO todo_counter_shards (shard)
+SELECT generate_series(0, 15)
+ON CONFLICT (shard) DO NOTHING;
+
+UPDATE todo_counter_shards
+SET total = 0, done = 0, archived = 0, updated_at = NOW()
+WHERE shard <> 0;
+
+UPDATE todo_counter_shards
+SET (total, done, archived) = (
+        SELECT COUNT(*),
+            COUNT(*) FILTER (WHERE done),
+            COUNT(*) FILTER (WHERE archived_at IS NOT NULL)
+        FROM todos
+    ),
+    updated_at = NOW()
+WHERE shard = 0;
+
+DROP TABLE IF EXISTS todo_counters;
+
+COMMIT;
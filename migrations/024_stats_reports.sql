@@ -0,0 +1,10 @@
+-- 定时报表的投递记录：每个周期一行，副本先认领再投递，投递成功后记录 sent_at，
+-- 多个 stats-api 副本或重启后都不会重复发送同一周期的报表
+CREATE TABLE IF NOT EXISTS stats_reports (
+    period_start TIMESTAMPTZ NOT NULL,
+    period_end TIMESTAMPTZ NOT NULL,
+    claimed_until TIMESTAMPTZ,
+    sent_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (period_start, period_end)
+);